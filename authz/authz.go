@@ -0,0 +1,160 @@
+// Package authz provides authorization middleware for mig routes.
+//
+// Authentication middleware stores the caller on the Context with
+// Context.SetPrincipal. BasicAuth and DigestAuth store an *User by default;
+// APIKey and custom middleware must store a value implementing Principal.
+// The middleware in this package inspects that principal and rejects the
+// request with 401 Unauthorized when there is no principal at all, or with
+// 403 Forbidden when the principal is not allowed or does not implement
+// Principal.
+//
+//	admin := m.Group("/admin", authn, authz.RequireRole("admin"))
+//	owner := authz.Require(authz.AnyOf(authz.HasRole("admin"), authz.Owner("id")))
+//	m.PUT("/users/{id}", owner(updateUser))
+package authz
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/levmv/mig"
+)
+
+// Principal is an authenticated caller. Store values implementing it with
+// Context.SetPrincipal to make them visible to this package.
+type Principal interface {
+	// ID returns a stable identifier, compared by Owner policies.
+	ID() string
+	HasRole(role string) bool
+	HasScope(scope string) bool
+	HasPermission(permission string) bool
+}
+
+// User is a simple Principal backed by slices.
+type User struct {
+	Subject     string
+	Roles       []string
+	Scopes      []string
+	Permissions []string
+}
+
+func (u *User) ID() string { return u.Subject }
+
+func (u *User) HasRole(role string) bool { return slices.Contains(u.Roles, role) }
+
+func (u *User) HasScope(scope string) bool { return slices.Contains(u.Scopes, scope) }
+
+func (u *User) HasPermission(permission string) bool {
+	return slices.Contains(u.Permissions, permission)
+}
+
+// PrincipalFrom returns the Principal stored on c, or nil if there is none
+// or the stored value does not implement Principal.
+func PrincipalFrom(c *mig.Context) Principal {
+	p, _ := c.Principal().(Principal)
+	return p
+}
+
+// Policy decides whether p may perform the current request.
+// p is never nil when a policy is called.
+type Policy func(c *mig.Context, p Principal) bool
+
+// Require returns a middleware that allows the request only if policy allows it.
+func Require(policy Policy) mig.MiddlewareFunc {
+	return func(next mig.Handler) mig.Handler {
+		return func(c *mig.Context) error {
+			if c.Principal() == nil {
+				return mig.NewHTTPError(http.StatusUnauthorized)
+			}
+			p := PrincipalFrom(c)
+			if p == nil || !policy(c, p) {
+				return mig.NewHTTPError(http.StatusForbidden)
+			}
+			return next(c)
+		}
+	}
+}
+
+// Authenticated returns a middleware that only requires a principal to be present.
+func Authenticated() mig.MiddlewareFunc {
+	return Require(func(*mig.Context, Principal) bool { return true })
+}
+
+// RequireRole allows principals having any of the given roles.
+func RequireRole(roles ...string) mig.MiddlewareFunc {
+	return Require(HasRole(roles...))
+}
+
+// RequireScope allows principals having all of the given scopes.
+func RequireScope(scopes ...string) mig.MiddlewareFunc {
+	return Require(HasScope(scopes...))
+}
+
+// RequirePermission allows principals having all of the given permissions.
+func RequirePermission(permissions ...string) mig.MiddlewareFunc {
+	return Require(HasPermission(permissions...))
+}
+
+// HasRole is a Policy satisfied by any of the given roles.
+func HasRole(roles ...string) Policy {
+	return func(_ *mig.Context, p Principal) bool {
+		return slices.ContainsFunc(roles, p.HasRole)
+	}
+}
+
+// HasScope is a Policy satisfied when all the given scopes are granted.
+func HasScope(scopes ...string) Policy {
+	return func(_ *mig.Context, p Principal) bool {
+		for _, s := range scopes {
+			if !p.HasScope(s) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// HasPermission is a Policy satisfied when all the given permissions are granted.
+func HasPermission(permissions ...string) Policy {
+	return func(_ *mig.Context, p Principal) bool {
+		for _, perm := range permissions {
+			if !p.HasPermission(perm) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Owner is a Policy satisfied when the path value named param equals the
+// principal's ID, e.g. Owner("id") on "/users/{id}".
+func Owner(param string) Policy {
+	return func(c *mig.Context, p Principal) bool {
+		v := c.PathValue(param)
+		return v != "" && v == p.ID()
+	}
+}
+
+// AnyOf combines policies so that one of them must allow the request.
+func AnyOf(policies ...Policy) Policy {
+	return func(c *mig.Context, p Principal) bool {
+		for _, policy := range policies {
+			if policy(c, p) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// AllOf combines policies so that all of them must allow the request.
+func AllOf(policies ...Policy) Policy {
+	return func(c *mig.Context, p Principal) bool {
+		for _, policy := range policies {
+			if !policy(c, p) {
+				return false
+			}
+		}
+		return true
+	}
+}
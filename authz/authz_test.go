@@ -0,0 +1,150 @@
+package authz_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/levmv/mig"
+	"github.com/levmv/mig/authz"
+	"github.com/levmv/mig/middleware"
+)
+
+func assertEqual(t *testing.T, expected, actual any, msg string) {
+	t.Helper()
+	if expected != actual {
+		t.Fatalf("%s: mismatch\n\texp: %v\n\tgot: %v", msg, expected, actual)
+	}
+}
+
+// withPrincipal simulates authentication middleware by taking the user from a header.
+func withPrincipal(users map[string]*authz.User) mig.MiddlewareFunc {
+	return func(next mig.Handler) mig.Handler {
+		return func(c *mig.Context) error {
+			if u, ok := users[c.Request.Header.Get("X-User")]; ok {
+				c.SetPrincipal(u)
+			}
+			return next(c)
+		}
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	m := mig.New(context.Background())
+	m.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	users := map[string]*authz.User{
+		"alice": {Subject: "1", Roles: []string{"admin"}},
+		"bob":   {Subject: "2", Roles: []string{"user"}, Scopes: []string{"read", "write"}, Permissions: []string{"reports.view"}},
+		"carol": {Subject: "3", Scopes: []string{"read"}},
+	}
+	m.Use(withPrincipal(users))
+
+	ok := func(c *mig.Context) error { return c.Raw([]byte("OK")) }
+
+	admin := m.Group("/admin", authz.RequireRole("admin"))
+	admin.GET("/panel", ok)
+
+	api := m.Group("/api", authz.Authenticated())
+	api.PUT("/data", authz.RequireScope("read", "write")(ok))
+	api.GET("/reports", authz.RequirePermission("reports.view")(ok))
+	api.GET("/users/{id}", authz.Require(authz.AnyOf(authz.HasRole("admin"), authz.Owner("id")))(ok))
+
+	testCases := []struct {
+		name         string
+		user         string
+		method       string
+		path         string
+		expectStatus int
+	}{
+		{"Anonymous is unauthorized", "", http.MethodGet, "/admin/panel", http.StatusUnauthorized},
+		{"Admin role allowed", "alice", http.MethodGet, "/admin/panel", http.StatusOK},
+		{"Missing role forbidden", "bob", http.MethodGet, "/admin/panel", http.StatusForbidden},
+		{"All scopes present", "bob", http.MethodPut, "/api/data", http.StatusOK},
+		{"Scope missing", "carol", http.MethodPut, "/api/data", http.StatusForbidden},
+		{"Permission present", "bob", http.MethodGet, "/api/reports", http.StatusOK},
+		{"Permission missing", "carol", http.MethodGet, "/api/reports", http.StatusForbidden},
+		{"Owner allowed", "bob", http.MethodGet, "/api/users/2", http.StatusOK},
+		{"Non-owner forbidden", "bob", http.MethodGet, "/api/users/3", http.StatusForbidden},
+		{"Admin overrides owner check", "alice", http.MethodGet, "/api/users/3", http.StatusOK},
+		{"Anonymous on authenticated group", "", http.MethodGet, "/api/users/2", http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.user != "" {
+				req.Header.Set("X-User", tc.user)
+			}
+			rec := httptest.NewRecorder()
+
+			m.Mux.ServeHTTP(rec, req)
+
+			assertEqual(t, tc.expectStatus, rec.Code, "Status code mismatch")
+		})
+	}
+}
+
+func TestAuthorization_WithAuthMiddleware(t *testing.T) {
+	m := mig.New(context.Background())
+	m.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	ok := func(c *mig.Context) error { return c.Raw([]byte(authz.PrincipalFrom(c).ID())) }
+	basic := middleware.BasicAuthWithConfig(middleware.BasicAuthConfig{
+		IsAllowed: func(user, password string) bool { return password == "secret" },
+		Principal: func(user string) authz.Principal {
+			if user == "alice" {
+				return &authz.User{Subject: user, Roles: []string{"admin"}}
+			}
+			return &authz.User{Subject: user}
+		},
+	})
+	m.Group("/admin", basic, authz.RequireRole("admin")).GET("/panel", ok)
+
+	plain := middleware.BasicAuthWithConfig(middleware.BasicAuthConfig{
+		IsAllowed: func(user, password string) bool { return password == "secret" },
+	})
+	m.Group("/default", plain, authz.Authenticated()).GET("/me", ok)
+
+	// A principal that does not implement authz.Principal is forbidden.
+	apiKey := middleware.APIKey(func(key string) (any, bool) { return "service", key == "k" })
+	m.Group("/api", apiKey, authz.Authenticated()).GET("/data", ok)
+
+	testCases := []struct {
+		name         string
+		path         string
+		user         string
+		apiKey       string
+		expectStatus int
+		expectBody   string
+	}{
+		{"Admin role allowed", "/admin/panel", "alice", "", http.StatusOK, "alice"},
+		{"Missing role forbidden", "/admin/panel", "bob", "", http.StatusForbidden, ""},
+		{"Unauthenticated", "/admin/panel", "", "", http.StatusUnauthorized, ""},
+		{"Default principal", "/default/me", "bob", "", http.StatusOK, "bob"},
+		{"Foreign principal type", "/api/data", "", "k", http.StatusForbidden, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.user != "" {
+				req.SetBasicAuth(tc.user, "secret")
+			}
+			if tc.apiKey != "" {
+				req.Header.Set(middleware.APIKeyHeader, tc.apiKey)
+			}
+			rec := httptest.NewRecorder()
+
+			m.Mux.ServeHTTP(rec, req)
+
+			assertEqual(t, tc.expectStatus, rec.Code, "Status code mismatch")
+			if tc.expectBody != "" {
+				assertEqual(t, tc.expectBody, rec.Body.String(), "Body mismatch")
+			}
+		})
+	}
+}
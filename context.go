@@ -3,10 +3,19 @@ package mig
 import (
 	"bytes"
 	"context"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
 	"log/slog"
+	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"strings"
+	"time"
 )
 
 // Context represents the context of an HTTP request. It provides methods to
@@ -23,6 +32,9 @@ type Context struct {
 	Logger   *slog.Logger
 	Mig      *Mig
 	query    url.Values
+	route    *Route
+	uploads  map[string][]*UploadedFile
+	cleanups []func()
 }
 
 // Reset reuses the context instance for a new request.
@@ -35,6 +47,9 @@ func (c *Context) Reset(r *http.Request, rw http.ResponseWriter) {
 	c.Response.status = 0
 	c.Response.written = 0
 	c.query = nil
+	c.route = nil
+	c.uploads = nil
+	c.cleanups = c.cleanups[:0]
 	c.Logger = c.Mig.Logger // Reset to base logger
 }
 
@@ -49,6 +64,57 @@ func (c *Context) Get(name any) any {
 	return c.Request.Context().Value(name)
 }
 
+// OnCleanup registers f to run after the request is finished, including
+// error handling, e.g. to remove temporary files.
+func (c *Context) OnCleanup(f func()) {
+	c.cleanups = append(c.cleanups, f)
+}
+
+func (c *Context) runCleanups() {
+	for i := len(c.cleanups) - 1; i >= 0; i-- {
+		c.cleanups[i]()
+	}
+}
+
+// Route returns the route that matched the request, or nil if the handler
+// was not invoked through a registered route (e.g. via Mig.Execute).
+func (c *Context) Route() *Route {
+	return c.route
+}
+
+// ClientCertificate returns the client certificate verified during the TLS
+// handshake (mTLS), or nil if the client did not present a verified one.
+func (c *Context) ClientCertificate() *x509.Certificate {
+	if c.Request.TLS == nil || len(c.Request.TLS.VerifiedChains) == 0 {
+		return nil
+	}
+	return c.Request.TLS.VerifiedChains[0][0]
+}
+
+// Protocol returns the protocol of the request as an ALPN identifier:
+// "h2" for HTTP/2 over TLS, "h2c" for cleartext HTTP/2, or "http/1.1".
+func (c *Context) Protocol() string {
+	if c.Request.ProtoMajor == 2 {
+		if c.Request.TLS != nil {
+			return "h2"
+		}
+		return "h2c"
+	}
+	return strings.ToLower(c.Request.Proto)
+}
+
+// RealIP returns the IP address of the client, without the port. It is
+// taken from the connection, so forwarding headers set by clients can't
+// spoof it; behind a TCP load balancer, enable Mig.ProxyProtocol to get the
+// original client address.
+func (c *Context) RealIP() string {
+	host, _, err := net.SplitHostPort(c.Request.RemoteAddr)
+	if err != nil {
+		return c.Request.RemoteAddr
+	}
+	return host
+}
+
 // PathValue returns the value of a URL path parameter by its name.
 // For example, for a route registered as "/users/{id}", PathValue("id") will
 // return the corresponding value from the request URL.
@@ -97,14 +163,21 @@ func (c *Context) BindJSON(out any) error {
 	return nil
 }
 
+// JSON sends out as JSON with status 200. See JSONStatus.
 func (c *Context) JSON(out any) error {
-	res, err := json.Marshal(out)
-	if err != nil {
+	return c.JSONStatus(http.StatusOK, out)
+}
+
+// JSONStatus sends out as JSON with the given status code. The output is
+// formatted according to Mig.JSON; nothing is written if encoding fails.
+func (c *Context) JSONStatus(code int, out any) error {
+	var buf bytes.Buffer
+	if err := c.encodeJSON(&buf, out); err != nil {
 		return err
 	}
 	c.Response.Header().Set("Content-Type", "application/json; charset=utf-8")
-	c.Response.WriteHeader(http.StatusOK)
-	_, err = c.Response.Write(res)
+	c.Response.WriteHeader(code)
+	_, err := c.Response.Write(buf.Bytes())
 	return err
 }
 
@@ -147,6 +220,118 @@ func (c *Context) Redirect(code int, url string) error {
 	return nil
 }
 
+// File sends the contents of the file at path, with support for conditional
+// and range requests. Missing files and directories are reported as
+// ErrNotFound. Call Attachment first to make the browser download it.
+func (c *Context) File(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ErrNotFound
+		}
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		return ErrNotFound
+	}
+	http.ServeContent(c.Response, c.Request, fi.Name(), fi.ModTime(), f)
+	return nil
+}
+
+// ServeContent sends the content of rs, handling If-Modified-Since, If-Range
+// and Range requests, including multipart/byteranges responses. If the
+// Content-Type header is not set, it is sniffed from the content. Set an
+// ETag header beforehand to enable If-None-Match and If-Range with it.
+func (c *Context) ServeContent(rs io.ReadSeeker, modtime time.Time) error {
+	http.ServeContent(c.Response, c.Request, "", modtime, rs)
+	return nil
+}
+
+// Stream copies r to the response as it is read, flushing after each chunk,
+// e.g. to relay output of a long running process.
+func (c *Context) Stream(contentType string, r io.Reader) error {
+	c.Response.Header().Set("Content-Type", contentType)
+	c.Response.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(c.Response)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if _, werr := c.Response.Write(buf[:n]); werr != nil {
+				return werr
+			}
+			rc.Flush()
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Attachment sets the Content-Disposition header so that the response is
+// downloaded and saved as name. Non-ASCII names are encoded per RFC 6266,
+// with an ASCII fallback for old clients.
+func (c *Context) Attachment(name string) {
+	c.Response.Header().Set("Content-Disposition", contentDisposition("attachment", name))
+}
+
+func contentDisposition(kind, name string) string {
+	var fallback, encoded strings.Builder
+	ascii := true
+	for _, r := range name {
+		switch {
+		case r < 0x20 || r >= 0x7f:
+			ascii = false
+			fallback.WriteByte('_')
+		case r == '"' || r == '\\':
+			fallback.WriteByte('_')
+		default:
+			fallback.WriteRune(r)
+		}
+	}
+	v := kind + `; filename="` + fallback.String() + `"`
+	if ascii && fallback.String() == name {
+		return v
+	}
+	for _, b := range []byte(name) {
+		if isAttrChar(b) {
+			encoded.WriteByte(b)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+	return v + "; filename*=UTF-8''" + encoded.String()
+}
+
+// isAttrChar reports whether b may appear unencoded in an RFC 8187 value.
+func isAttrChar(b byte) bool {
+	return 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9' ||
+		strings.IndexByte("!#$&+-.^_`|~", b) >= 0
+}
+
+// ResponseStatus returns the status code the client receives for this request,
+// given the error returned by the rest of the handler chain. Unlike
+// Response.Status, it accounts for an error the ErrorHandler has not written
+// yet, which makes it suitable for logging and metrics middleware.
+func (c *Context) ResponseStatus(err error) int {
+	if err == nil || c.Response.status != 0 {
+		return c.Response.Status()
+	}
+	var e *HTTPError
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return http.StatusInternalServerError
+}
+
 // The key for storing the request ID in a context.Context.
 type requestIDKey struct{}
 
@@ -182,3 +367,24 @@ func RequestIDFromContext(ctx context.Context) string {
 	}
 	return ""
 }
+
+// The key for storing the authenticated principal in a context.Context.
+type principalKey struct{}
+
+// SetPrincipal attaches the authenticated principal (user, service, API client)
+// to this Context and its Request.Context. Authentication middleware calls this
+// so handlers and authorization code can find out who is making the request.
+func (c *Context) SetPrincipal(p any) {
+	ctx := context.WithValue(c.Request.Context(), principalKey{}, p)
+	c.Request = c.Request.WithContext(ctx)
+}
+
+// Principal returns the authenticated principal, or nil if none set.
+func (c *Context) Principal() any {
+	return PrincipalFromContext(c.Request.Context())
+}
+
+// PrincipalFromContext extracts the authenticated principal from a stdlib context.Context.
+func PrincipalFromContext(ctx context.Context) any {
+	return ctx.Value(principalKey{})
+}
@@ -5,9 +5,12 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/levmv/mig"
 )
@@ -230,3 +233,106 @@ func TestContext_ResponseWriters(t *testing.T) {
 		assertEqual(t, htmlContent, rec.Body.String(), "HTML body is incorrect")
 	})
 }
+
+func TestContext_Route(t *testing.T) {
+	m := mig.New(context.Background())
+	var got mig.Route
+
+	handler := func(c *mig.Context) error {
+		got = *c.Route()
+		return c.Raw([]byte("OK"))
+	}
+
+	api := m.Group("/api")
+	api.GET("/users/{id}", handler).Name = "users.show"
+	m.Any("/any", handler)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users/42", nil)
+	m.Mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	assertEqual(t, mig.Route{Method: http.MethodGet, Pattern: "/api/users/{id}", Group: "/api", Name: "users.show"}, got, "Route mismatch")
+
+	req = httptest.NewRequest(http.MethodPost, "/any", nil)
+	m.Mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	assertEqual(t, mig.Route{Pattern: "/any"}, got, "Any route mismatch")
+	assertEqual(t, 2, len(m.Routes()), "Registered route count")
+}
+
+func TestContext_Files(t *testing.T) {
+	m := mig.New(context.Background())
+	restoreLogger := setupSilentLogger(m)
+	defer restoreLogger()
+
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "data.txt")
+	assertNoError(t, os.WriteFile(filePath, []byte("0123456789"), 0o600), "WriteFile failed")
+	modTime := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	assertNoError(t, os.Chtimes(filePath, modTime, modTime), "Chtimes failed")
+
+	var written int
+	m.Use(func(next mig.Handler) mig.Handler {
+		return func(c *mig.Context) error {
+			err := next(c)
+			written = c.Response.Written()
+			return err
+		}
+	})
+	m.GET("/file", func(c *mig.Context) error {
+		c.Attachment("data.txt")
+		return c.File(filePath)
+	})
+	m.GET("/missing", func(c *mig.Context) error { return c.File(filepath.Join(dir, "nope")) })
+	m.GET("/content", func(c *mig.Context) error {
+		c.Response.Header().Set("ETag", `"v1"`)
+		c.Response.Header().Set("Content-Type", "text/plain")
+		return c.ServeContent(strings.NewReader("abcdefghij"), modTime)
+	})
+	m.GET("/stream", func(c *mig.Context) error {
+		c.Attachment("отчёт \"2024\".csv")
+		return c.Stream("text/csv", strings.NewReader("a,b\n1,2\n"))
+	})
+
+	do := func(target string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		rec := httptest.NewRecorder()
+		m.Mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("/file")
+	assertEqual(t, "0123456789", rec.Body.String(), "File body")
+	assertEqual(t, `attachment; filename="data.txt"`, rec.Header().Get("Content-Disposition"), "ASCII attachment")
+	assertEqual(t, modTime.Format(http.TimeFormat), rec.Header().Get("Last-Modified"), "Last-Modified")
+	assertEqual(t, 10, written, "Written bytes")
+
+	rec = do("/file", "Range", "bytes=2-4")
+	assertEqual(t, http.StatusPartialContent, rec.Code, "Range status")
+	assertEqual(t, "234", rec.Body.String(), "Range body")
+	assertEqual(t, 3, written, "Written bytes for range")
+
+	rec = do("/content", "Range", "bytes=0-1,8-9")
+	assertEqual(t, http.StatusPartialContent, rec.Code, "Multi-range status")
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "multipart/byteranges; boundary=") {
+		t.Fatalf("expected multipart/byteranges, got %q", rec.Header().Get("Content-Type"))
+	}
+
+	rec = do("/content", "Range", "bytes=0-1", "If-Range", `"v0"`)
+	assertEqual(t, http.StatusOK, rec.Code, "Stale If-Range sends the full content")
+	assertEqual(t, "abcdefghij", rec.Body.String(), "Full body")
+
+	rec = do("/content", "Range", "bytes=0-1", "If-Range", `"v1"`)
+	assertEqual(t, "ab", rec.Body.String(), "Matching If-Range")
+
+	assertEqual(t, http.StatusNotFound, do("/missing").Code, "Missing file")
+
+	rec = do("/stream")
+	assertEqual(t, "a,b\n1,2\n", rec.Body.String(), "Stream body")
+	assertEqual(t, "text/csv", rec.Header().Get("Content-Type"), "Stream content type")
+	assertEqual(t, true, rec.Flushed, "Stream flushes")
+	assertEqual(t, `attachment; filename="_____ _2024_.csv"; filename*=UTF-8''%D0%BE%D1%82%D1%87%D1%91%D1%82%20%222024%22.csv`,
+		rec.Header().Get("Content-Disposition"), "RFC 6266 attachment")
+}
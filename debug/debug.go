@@ -0,0 +1,108 @@
+// Package debug mounts profiling and runtime diagnostics endpoints on a mig
+// route group. Because they are ordinary routes, they run behind the group's
+// middleware, so protect the group with authentication or an IP allowlist:
+//
+//	admin := m.Group("/admin", middleware.BasicAuthWithConfig(cfg))
+//	debug.Mount(admin.Group("/debug"))
+//
+// This registers:
+//
+//	/pprof/        pprof index and profiles, usable with `go tool pprof`
+//	/vars          expvar variables as JSON
+//	/runtime       runtime statistics as JSON
+//	/goroutines    full goroutine stack dump
+//	/buildinfo     module build information
+//
+// Importing this package imports net/http/pprof and expvar, which register
+// handlers on http.DefaultServeMux. Do not serve http.DefaultServeMux publicly.
+package debug
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	rdebug "runtime/debug"
+	rpprof "runtime/pprof"
+	"time"
+
+	"github.com/levmv/mig"
+)
+
+var startTime = time.Now()
+
+// Mount registers the diagnostics endpoints on g.
+func Mount(g *mig.RouteGroup) {
+	g.GET("/pprof/{$}", mig.WrapHandler(http.HandlerFunc(pprof.Index)))
+	g.GET("/pprof/cmdline", mig.WrapHandler(http.HandlerFunc(pprof.Cmdline)))
+	g.GET("/pprof/profile", mig.WrapHandler(http.HandlerFunc(pprof.Profile)))
+	g.GET("/pprof/symbol", mig.WrapHandler(http.HandlerFunc(pprof.Symbol)))
+	g.POST("/pprof/symbol", mig.WrapHandler(http.HandlerFunc(pprof.Symbol)))
+	g.GET("/pprof/trace", mig.WrapHandler(http.HandlerFunc(pprof.Trace)))
+	// pprof.Index only serves named profiles under /debug/pprof/, so route
+	// them explicitly to work under any prefix.
+	g.GET("/pprof/{profile}", func(c *mig.Context) error {
+		name := c.PathValue("profile")
+		if rpprof.Lookup(name) == nil {
+			return mig.ErrNotFound
+		}
+		pprof.Handler(name).ServeHTTP(c.Response, c.Request)
+		return nil
+	})
+
+	g.GET("/vars", mig.WrapHandler(expvar.Handler()))
+	g.GET("/runtime", Runtime)
+	g.GET("/goroutines", Goroutines)
+	g.GET("/buildinfo", BuildInfo)
+}
+
+// Runtime responds with a JSON snapshot of runtime statistics.
+func Runtime(c *mig.Context) error {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	host, _ := os.Hostname()
+
+	return c.JSON(map[string]any{
+		"go_version": runtime.Version(),
+		"goos":       runtime.GOOS,
+		"goarch":     runtime.GOARCH,
+		"hostname":   host,
+		"pid":        os.Getpid(),
+		"uptime":     time.Since(startTime).Round(time.Second).String(),
+		"num_cpu":    runtime.NumCPU(),
+		"gomaxprocs": runtime.GOMAXPROCS(0),
+		"goroutines": runtime.NumGoroutine(),
+		"cgo_calls":  runtime.NumCgoCall(),
+		"memory": map[string]any{
+			"alloc":          ms.Alloc,
+			"total_alloc":    ms.TotalAlloc,
+			"sys":            ms.Sys,
+			"heap_alloc":     ms.HeapAlloc,
+			"heap_inuse":     ms.HeapInuse,
+			"heap_objects":   ms.HeapObjects,
+			"stack_inuse":    ms.StackInuse,
+			"mallocs":        ms.Mallocs,
+			"frees":          ms.Frees,
+			"num_gc":         ms.NumGC,
+			"pause_total_ns": ms.PauseTotalNs,
+			"next_gc":        ms.NextGC,
+		},
+	})
+}
+
+// Goroutines responds with the stack traces of all goroutines.
+func Goroutines(c *mig.Context) error {
+	c.Response.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	return rpprof.Lookup("goroutine").WriteTo(c.Response, 2)
+}
+
+// BuildInfo responds with the build information embedded in the binary,
+// in the format of `go version -m`.
+func BuildInfo(c *mig.Context) error {
+	info, ok := rdebug.ReadBuildInfo()
+	if !ok {
+		return mig.ErrNotFound
+	}
+	return c.String(http.StatusOK, info.String())
+}
@@ -0,0 +1,61 @@
+package debug_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/levmv/mig"
+	"github.com/levmv/mig/debug"
+	"github.com/levmv/mig/middleware"
+)
+
+func TestMount(t *testing.T) {
+	m := mig.New(context.Background())
+	m.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	admin := m.Group("/admin", middleware.BasicAuthWithConfig(middleware.BasicAuthConfig{
+		IsAllowed: func(user, password string) bool { return user == "root" && password == "secret" },
+	}))
+	debug.Mount(admin.Group("/debug"))
+
+	testCases := []struct {
+		path         string
+		auth         bool
+		expectStatus int
+		expectBody   string
+	}{
+		{"/admin/debug/pprof/", false, http.StatusUnauthorized, ""},
+		{"/admin/debug/pprof/", true, http.StatusOK, "Types of profiles available"},
+		{"/admin/debug/pprof/goroutine?debug=1", true, http.StatusOK, "goroutine profile:"},
+		{"/admin/debug/pprof/nope", true, http.StatusNotFound, ""},
+		{"/admin/debug/pprof/cmdline", true, http.StatusOK, ""},
+		{"/admin/debug/vars", true, http.StatusOK, `"memstats"`},
+		{"/admin/debug/runtime", true, http.StatusOK, `"goroutines"`},
+		{"/admin/debug/goroutines", true, http.StatusOK, "goroutine "},
+		{"/admin/debug/buildinfo", true, http.StatusOK, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.auth {
+				req.SetBasicAuth("root", "secret")
+			}
+			rec := httptest.NewRecorder()
+
+			m.Mux.ServeHTTP(rec, req)
+
+			if rec.Code != tc.expectStatus {
+				t.Fatalf("status mismatch: exp %d, got %d", tc.expectStatus, rec.Code)
+			}
+			if !strings.Contains(rec.Body.String(), tc.expectBody) {
+				t.Errorf("body does not contain %q:\n%s", tc.expectBody, rec.Body.String())
+			}
+		})
+	}
+}
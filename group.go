@@ -1,6 +1,9 @@
 package mig
 
-import "net/http"
+import (
+	"net/http"
+	"strings"
+)
 
 // RouteGroup represents a group of routes with shared middleware.
 type RouteGroup struct {
@@ -10,6 +13,20 @@ type RouteGroup struct {
 	ParentRouter *RouteGroup
 }
 
+// Route describes a registered route. It is available to handlers and
+// middleware through Context.Route, e.g. for logging and metrics.
+type Route struct {
+	// Method is the HTTP method, or "" for routes matching any method.
+	Method string
+	// Pattern is the full ServeMux pattern without the method, including the
+	// group prefix, e.g. "/api/users/{id}".
+	Pattern string
+	// Group is the prefix of the group the route was registered on.
+	Group string
+	// Name is an optional name set by the application.
+	Name string
+}
+
 // Group creates a new routing group with a common prefix and shared middleware.
 func (rg *RouteGroup) Group(prefix string, middlewares ...MiddlewareFunc) *RouteGroup {
 	g := &RouteGroup{
@@ -30,19 +47,27 @@ func (rg *RouteGroup) Use(middlewares ...MiddlewareFunc) {
 // HandleRaw registers a handler for a raw, unprocessed http.ServeMux pattern.
 // The group's path prefix is NOT applied. All group middleware is applied.
 // This is the advanced method for use cases like host-based routing.
-func (rg *RouteGroup) HandleRaw(pattern string, handler Handler) {
+func (rg *RouteGroup) HandleRaw(pattern string, handler Handler) *Route {
+	route := &Route{Pattern: pattern, Group: rg.Prefix}
+	if method, rest, ok := strings.Cut(pattern, " "); ok {
+		route.Method = method
+		route.Pattern = strings.TrimLeft(rest, " \t")
+	}
+
 	fhandler := handler
 	for i := len(rg.middlewares) - 1; i >= 0; i-- {
 		fhandler = rg.middlewares[i](fhandler)
 	}
 	rg.Mig.Mux.HandleFunc(pattern, func(rw http.ResponseWriter, r *http.Request) {
-		rg.Mig.Execute(fhandler, rw, r)
+		rg.Mig.execute(route, fhandler, rw, r)
 	})
+	rg.Mig.routes = append(rg.Mig.routes, route)
+	return route
 }
 
 // Handle registers a handler for a given HTTP method and path.
 // It correctly applies the group's path prefix.
-func (rg *RouteGroup) Handle(method, path string, handler Handler) {
+func (rg *RouteGroup) Handle(method, path string, handler Handler) *Route {
 	fullPath := rg.Prefix + path
 	var pattern string
 
@@ -52,30 +77,30 @@ func (rg *RouteGroup) Handle(method, path string, handler Handler) {
 		pattern = fullPath
 	}
 
-	rg.HandleRaw(pattern, handler)
+	return rg.HandleRaw(pattern, handler)
 }
 
 // GET registers a GET and HEAD handler for a path.
-func (rg *RouteGroup) GET(path string, handler Handler) {
-	rg.Handle(http.MethodGet, path, handler)
+func (rg *RouteGroup) GET(path string, handler Handler) *Route {
+	return rg.Handle(http.MethodGet, path, handler)
 }
 
 // POST registers a POST handler for a path.
-func (rg *RouteGroup) POST(path string, handler Handler) {
-	rg.Handle(http.MethodPost, path, handler)
+func (rg *RouteGroup) POST(path string, handler Handler) *Route {
+	return rg.Handle(http.MethodPost, path, handler)
 }
 
 // PUT registers a PUT handler for a path.
-func (rg *RouteGroup) PUT(path string, handler Handler) {
-	rg.Handle(http.MethodPut, path, handler)
+func (rg *RouteGroup) PUT(path string, handler Handler) *Route {
+	return rg.Handle(http.MethodPut, path, handler)
 }
 
 // DELETE registers a DELETE handler for a path.
-func (rg *RouteGroup) DELETE(path string, handler Handler) {
-	rg.Handle(http.MethodDelete, path, handler)
+func (rg *RouteGroup) DELETE(path string, handler Handler) *Route {
+	return rg.Handle(http.MethodDelete, path, handler)
 }
 
 // Any registers a handler that matches any HTTP method for a path.
-func (rg *RouteGroup) Any(path string, handler Handler) {
-	rg.Handle("", path, handler)
+func (rg *RouteGroup) Any(path string, handler Handler) *Route {
+	return rg.Handle("", path, handler)
 }
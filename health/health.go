@@ -0,0 +1,223 @@
+// Package health provides liveness and readiness endpoints backed by named checks.
+//
+//	h := health.New()
+//	h.Register("db", db.PingContext, health.CheckOptions{Timeout: time.Second})
+//	h.Mount(m.Group(""))
+//
+//	m.DrainPeriod = 10 * time.Second // /readyz fails for 10s before the server stops
+//
+// /livez reports whether the process is alive and runs only checks registered
+// with Liveness set. /readyz runs all checks and also fails as soon as
+// Mig.Shutdown is called, so load balancers stop routing traffic during
+// Mig.DrainPeriod.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/levmv/mig"
+)
+
+// Check reports a problem with a dependency by returning an error.
+type Check func(ctx context.Context) error
+
+// CheckOptions configures a registered check.
+type CheckOptions struct {
+	// Timeout bounds a single run of the check. Default is 5 seconds.
+	Timeout time.Duration
+	// CacheTTL reuses the last result for this long, to keep frequent probes
+	// from hammering the dependency. Default is 0 (run on every probe).
+	CacheTTL time.Duration
+	// Liveness includes the check in /livez as well as /readyz. Use it only
+	// for failures a restart would fix.
+	Liveness bool
+}
+
+// Status values reported in responses.
+const (
+	StatusOK           = "ok"
+	StatusFail         = "fail"
+	StatusShuttingDown = "shutting_down"
+)
+
+// Health is a set of named checks. It is safe for concurrent use.
+type Health struct {
+	mu     sync.RWMutex
+	checks []*check
+}
+
+type check struct {
+	name string
+	fn   Check
+	opts CheckOptions
+
+	mu       sync.Mutex
+	last     CheckResult
+	lastTime time.Time
+	inflight *checkRun
+}
+
+// checkRun is a run of a check shared by all probes waiting for it.
+type checkRun struct {
+	done chan struct{}
+	res  CheckResult
+}
+
+// CheckResult is the outcome of one check, as reported in the JSON response.
+type CheckResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report is the JSON body of /livez and /readyz.
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+func New() *Health {
+	return &Health{}
+}
+
+// Register adds a named check. Registering a name twice panics.
+func (h *Health) Register(name string, fn Check, opts CheckOptions) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, c := range h.checks {
+		if c.name == name {
+			panic("health: check " + name + " registered twice")
+		}
+	}
+	h.checks = append(h.checks, &check{name: name, fn: fn, opts: opts})
+}
+
+// Mount registers GET /livez and /readyz on g.
+func (h *Health) Mount(g *mig.RouteGroup) {
+	g.GET("/livez", h.Livez)
+	g.GET("/readyz", h.Readyz)
+}
+
+// Livez is the liveness handler.
+func (h *Health) Livez(c *mig.Context) error {
+	return h.respond(c, h.Run(c.Request.Context(), true))
+}
+
+// Readyz is the readiness handler. It fails while the Mig is shutting down.
+func (h *Health) Readyz(c *mig.Context) error {
+	if c.Mig.ShuttingDown() {
+		return h.respond(c, Report{Status: StatusShuttingDown})
+	}
+	return h.respond(c, h.Run(c.Request.Context(), false))
+}
+
+// Run executes the checks concurrently and aggregates the results. If
+// livenessOnly is true, only checks registered with Liveness are run.
+func (h *Health) Run(ctx context.Context, livenessOnly bool) Report {
+	h.mu.RLock()
+	checks := make([]*check, 0, len(h.checks))
+	for _, c := range h.checks {
+		if !livenessOnly || c.opts.Liveness {
+			checks = append(checks, c)
+		}
+	}
+	h.mu.RUnlock()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(checks))}
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.run(ctx)
+		}()
+	}
+	wg.Wait()
+
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+// run returns the cached result or waits for a run of the check, joining
+// the one in flight if there is one. The check runs detached from ctx, so a
+// probe that goes away neither cancels it nor caches its cancellation.
+func (c *check) run(ctx context.Context) CheckResult {
+	c.mu.Lock()
+	if c.opts.CacheTTL > 0 && !c.lastTime.IsZero() && time.Since(c.lastTime) < c.opts.CacheTTL {
+		c.mu.Unlock()
+		return c.last
+	}
+	r := c.inflight
+	if r == nil {
+		r = &checkRun{done: make(chan struct{})}
+		c.inflight = r
+		go c.execute(context.WithoutCancel(ctx), r)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-r.done:
+		return r.res
+	case <-ctx.Done():
+		return CheckResult{Status: StatusFail, Error: ctx.Err().Error()}
+	}
+}
+
+func (c *check) execute(ctx context.Context, r *checkRun) {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				done <- fmt.Errorf("panic: %v", rec)
+			}
+		}()
+		done <- c.fn(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	r.res = CheckResult{Status: StatusOK, Duration: time.Since(start).Round(time.Microsecond).String()}
+	if err != nil {
+		r.res.Status = StatusFail
+		r.res.Error = err.Error()
+	}
+	c.mu.Lock()
+	c.last, c.lastTime = r.res, time.Now()
+	c.inflight = nil
+	c.mu.Unlock()
+	close(r.done)
+}
+
+func (h *Health) respond(c *mig.Context, r Report) error {
+	code := http.StatusOK
+	if r.Status != StatusOK {
+		code = http.StatusServiceUnavailable
+	}
+	c.Response.Header().Set("Content-Type", "application/json; charset=utf-8")
+	c.Response.Header().Set("Cache-Control", "no-store")
+	c.Response.WriteHeader(code)
+	return json.NewEncoder(c.Response).Encode(r)
+}
@@ -0,0 +1,143 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/levmv/mig"
+	"github.com/levmv/mig/health"
+)
+
+func assertEqual(t *testing.T, expected, actual any, msg string) {
+	t.Helper()
+	if expected != actual {
+		t.Fatalf("%s: mismatch\n\texp: %v\n\tgot: %v", msg, expected, actual)
+	}
+}
+
+func probe(t *testing.T, m *mig.Mig, path string) (int, health.Report) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	var r health.Report
+	if err := json.Unmarshal(rec.Body.Bytes(), &r); err != nil {
+		t.Fatalf("invalid JSON from %s: %v", path, err)
+	}
+	return rec.Code, r
+}
+
+func TestHealth(t *testing.T) {
+	m := mig.New(context.Background())
+	m.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	var dbDown atomic.Bool
+	var cacheRuns atomic.Int32
+
+	h := health.New()
+	h.Register("db", func(ctx context.Context) error {
+		if dbDown.Load() {
+			return errors.New("connection refused")
+		}
+		return nil
+	}, health.CheckOptions{})
+	h.Register("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(50 * time.Millisecond) // a check ignoring its context
+		return nil
+	}, health.CheckOptions{Timeout: 10 * time.Millisecond})
+	h.Register("cached", func(ctx context.Context) error {
+		cacheRuns.Add(1)
+		return nil
+	}, health.CheckOptions{CacheTTL: time.Hour, Liveness: true})
+	h.Mount(&m.RouteGroup)
+
+	code, r := probe(t, m, "/livez")
+	assertEqual(t, http.StatusOK, code, "Liveness status")
+	assertEqual(t, 1, len(r.Checks), "Liveness runs only liveness checks")
+
+	code, r = probe(t, m, "/readyz")
+	assertEqual(t, http.StatusServiceUnavailable, code, "Readiness with timed out check")
+	assertEqual(t, health.StatusOK, r.Checks["db"].Status, "db check")
+	assertEqual(t, health.StatusFail, r.Checks["slow"].Status, "slow check")
+	assertEqual(t, context.DeadlineExceeded.Error(), r.Checks["slow"].Error, "slow check error")
+	assertEqual(t, int32(1), cacheRuns.Load(), "Cached check should run once")
+
+	dbDown.Store(true)
+	_, r = probe(t, m, "/readyz")
+	assertEqual(t, "connection refused", r.Checks["db"].Error, "db check error")
+}
+
+func TestHealth_SharedRun(t *testing.T) {
+	var runs atomic.Int32
+	release := make(chan struct{})
+	h := health.New()
+	h.Register("db", func(ctx context.Context) error {
+		runs.Add(1)
+		<-release
+		return nil
+	}, health.CheckOptions{CacheTTL: time.Hour})
+
+	// A probe that goes away does not cancel the check or cache its failure.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r := h.Run(ctx, false)
+	assertEqual(t, context.Canceled.Error(), r.Checks["db"].Error, "Cancelled probe")
+
+	reports := make(chan health.Report, 3)
+	for range 3 {
+		go func() { reports <- h.Run(context.Background(), false) }()
+	}
+	time.Sleep(20 * time.Millisecond) // let the probes join the run
+	close(release)
+	for range 3 {
+		assertEqual(t, health.StatusOK, (<-reports).Status, "Concurrent probe")
+	}
+	assertEqual(t, int32(1), runs.Load(), "Concurrent probes share one run")
+	assertEqual(t, health.StatusOK, h.Run(context.Background(), false).Status, "Cached result")
+}
+
+func TestHealth_ReadinessFailsDuringDrain(t *testing.T) {
+	m := mig.New(context.Background())
+	m.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	m.DrainPeriod = 200 * time.Millisecond
+
+	h := health.New()
+	h.Mount(&m.RouteGroup)
+
+	if err := m.Start("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	code, _ := probe(t, m, "/readyz")
+	assertEqual(t, http.StatusOK, code, "Ready before shutdown")
+
+	done := make(chan error, 1)
+	go func() { done <- m.Shutdown() }()
+
+	deadline := time.Now().Add(100 * time.Millisecond)
+	for !m.ShuttingDown() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	code, r := probe(t, m, "/readyz")
+	assertEqual(t, http.StatusServiceUnavailable, code, "Readiness during drain")
+	assertEqual(t, health.StatusShuttingDown, r.Status, "Report status during drain")
+
+	code, _ = probe(t, m, "/livez")
+	assertEqual(t, http.StatusOK, code, "Liveness during drain")
+
+	select {
+	case err := <-done:
+		t.Fatalf("Shutdown returned before the drain period: %v", err)
+	default:
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
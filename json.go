@@ -0,0 +1,87 @@
+package mig
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"iter"
+)
+
+// JSONOptions configures JSON responses written by Context.JSON,
+// JSONStatus, JSONArray and NDJSON.
+type JSONOptions struct {
+	// Indent pretty-prints every response with this indent, e.g. "  ".
+	Indent string
+	// PrettyQueryParam names a query parameter, e.g. "pretty", that enables
+	// indentation for a request (?pretty). It uses Indent or two spaces.
+	PrettyQueryParam string
+	// DisableHTMLEscape keeps <, > and & in strings as they are instead of
+	// escaping them as \u003c etc. Escaping is only needed when JSON is
+	// embedded in HTML.
+	DisableHTMLEscape bool
+}
+
+func (c *Context) jsonEncoder(w io.Writer) *json.Encoder {
+	opts := &c.Mig.JSON
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(!opts.DisableHTMLEscape)
+	if opts.Indent != "" {
+		enc.SetIndent("", opts.Indent)
+	} else if opts.PrettyQueryParam != "" && c.HasQueryParam(opts.PrettyQueryParam) {
+		enc.SetIndent("", "  ")
+	}
+	return enc
+}
+
+// encodeJSON appends v to buf as formatted by jsonEncoder, without the
+// trailing newline added by json.Encoder.
+func (c *Context) encodeJSON(buf *bytes.Buffer, v any) error {
+	if err := c.jsonEncoder(buf).Encode(v); err != nil {
+		return err
+	}
+	buf.Truncate(buf.Len() - 1)
+	return nil
+}
+
+// JSONArray sends the items of seq as a JSON array, encoding and writing
+// them one by one instead of buffering the whole response. If encoding an
+// item fails, the response is cut short and the error is returned; the
+// status code has already been sent at that point.
+func JSONArray[T any](c *Context, code int, seq iter.Seq[T]) error {
+	c.Response.Header().Set("Content-Type", "application/json; charset=utf-8")
+	c.Response.WriteHeader(code)
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for item := range seq {
+		if buf.Len() == 0 { // the first item follows the opening bracket
+			buf.WriteByte(',')
+		}
+		if err := c.encodeJSON(&buf, item); err != nil {
+			return err
+		}
+		if _, err := c.Response.Write(buf.Bytes()); err != nil {
+			return err
+		}
+		buf.Reset()
+	}
+	buf.WriteByte(']')
+	_, err := c.Response.Write(buf.Bytes())
+	return err
+}
+
+// NDJSON sends the items of seq as newline-delimited JSON
+// (application/x-ndjson), one item per line, without buffering. It suits
+// large exports that clients process line by line. Indentation is never
+// applied. Errors are handled as in JSONArray.
+func NDJSON[T any](c *Context, code int, seq iter.Seq[T]) error {
+	c.Response.Header().Set("Content-Type", "application/x-ndjson")
+	c.Response.WriteHeader(code)
+	enc := json.NewEncoder(c.Response)
+	enc.SetEscapeHTML(!c.Mig.JSON.DisableHTMLEscape)
+	for item := range seq {
+		if err := enc.Encode(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
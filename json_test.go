@@ -0,0 +1,73 @@
+package mig_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/levmv/mig"
+)
+
+func TestJSONResponses(t *testing.T) {
+	type item struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	items := []item{{1, "a<b"}, {2, "c"}}
+
+	m := mig.New(context.Background())
+	restoreLogger := setupSilentLogger(m)
+	defer restoreLogger()
+
+	m.GET("/created", func(c *mig.Context) error {
+		return c.JSONStatus(http.StatusCreated, items[0])
+	})
+	m.GET("/array", func(c *mig.Context) error {
+		return mig.JSONArray(c, http.StatusOK, slices.Values(items))
+	})
+	m.GET("/empty", func(c *mig.Context) error {
+		return mig.JSONArray(c, http.StatusOK, slices.Values([]item{}))
+	})
+	m.GET("/export", func(c *mig.Context) error {
+		return mig.NDJSON(c, http.StatusOK, slices.Values(items))
+	})
+	m.GET("/render", func(c *mig.Context) error {
+		return c.Render(http.StatusCreated, items[0])
+	})
+	m.GET("/unencodable", func(c *mig.Context) error {
+		return c.JSONStatus(http.StatusCreated, func() {})
+	})
+
+	do := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		m.Mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	rec := do("/created")
+	assertEqual(t, http.StatusCreated, rec.Code, "Status")
+	assertEqual(t, `{"id":1,"name":"a\u003cb"}`, rec.Body.String(), "HTML escaped by default")
+
+	rec = do("/array")
+	assertEqual(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"), "Array content type")
+	assertEqual(t, `[{"id":1,"name":"a\u003cb"},{"id":2,"name":"c"}]`, rec.Body.String(), "Streamed array")
+	assertEqual(t, "[]", do("/empty").Body.String(), "Empty array")
+
+	rec = do("/export")
+	assertEqual(t, "application/x-ndjson", rec.Header().Get("Content-Type"), "NDJSON content type")
+	assertEqual(t, "{\"id\":1,\"name\":\"a\\u003cb\"}\n{\"id\":2,\"name\":\"c\"}\n", rec.Body.String(), "NDJSON lines")
+
+	rec = do("/unencodable")
+	assertEqual(t, http.StatusInternalServerError, rec.Code, "Encoding error is reported before writing")
+
+	m.JSON = mig.JSONOptions{PrettyQueryParam: "pretty", DisableHTMLEscape: true}
+	assertEqual(t, `{"id":1,"name":"a<b"}`, do("/created").Body.String(), "HTML escaping disabled")
+	assertEqual(t, "{\n  \"id\": 1,\n  \"name\": \"a<b\"\n}", do("/created?pretty").Body.String(), "Pretty via query")
+	assertEqual(t, do("/created?pretty").Body.String(), do("/render?pretty").Body.String(), "Render formats JSON like JSONStatus")
+	assertEqual(t, "[{\n  \"id\": 1,\n  \"name\": \"a<b\"\n},{\n  \"id\": 2,\n  \"name\": \"c\"\n}]", do("/array?pretty").Body.String(), "Pretty array")
+
+	m.JSON = mig.JSONOptions{Indent: "\t"}
+	assertEqual(t, "{\n\t\"id\": 1,\n\t\"name\": \"a\\u003cb\"\n}", do("/created").Body.String(), "Configured indent")
+}
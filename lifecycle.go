@@ -0,0 +1,131 @@
+package mig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Hook is a function run at a stage of the server lifecycle.
+// Each hook gets its own context, limited by Mig.HookTimeout.
+//
+// Hooks must be registered before Start; registration is not safe for
+// concurrent use with Start or Shutdown. The order of execution is:
+//
+//	Start:    OnStart hooks, in registration order; then listen and serve;
+//	          then OnListen hooks, in registration order.
+//	Shutdown: DrainPeriod; OnShutdown hooks, in reverse order; stop the
+//	          server, waiting for in-flight requests; OnStopped hooks, in reverse order.
+//
+// Teardown hooks (OnShutdown, OnStopped) all run even if some of them fail,
+// and their errors are joined. If Start fails, the OnStopped hooks registered
+// before the failed OnStart hook run too, so resources opened on start are
+// released; register each OnStopped hook after the OnStart hook it undoes.
+type Hook func(ctx context.Context) error
+
+// OnStart registers a hook run before the server starts listening, e.g. to
+// open database pools. An error aborts Start.
+func (m *Mig) OnStart(h Hook) {
+	m.hooks.start = append(m.hooks.start, h)
+}
+
+// OnListen registers a hook run once the server accepts connections.
+// An error makes Start shut the server down and return the error.
+func (m *Mig) OnListen(h Hook) {
+	m.hooks.listen = append(m.hooks.listen, h)
+}
+
+// OnShutdown registers a hook run when Shutdown begins, while in-flight
+// requests are still being served.
+func (m *Mig) OnShutdown(h Hook) {
+	m.hooks.shutdown = append(m.hooks.shutdown, h)
+}
+
+// OnStopped registers a hook run after the server stopped and in-flight
+// requests finished, e.g. to close database pools or flush buffers.
+func (m *Mig) OnStopped(h Hook) {
+	m.hooks.stopped = append(m.hooks.stopped, h)
+	m.hooks.stoppedAfter = append(m.hooks.stoppedAfter, len(m.hooks.start))
+}
+
+// RestartHook is a function run by Restart once the new process with the
+// given pid is ready.
+type RestartHook func(ctx context.Context, pid int) error
+
+// OnRestart registers a hook run by Restart after the new process is ready
+// and before this process shuts down, e.g. to tell a process supervisor
+// the new main PID. An error makes Restart kill the new process and keep
+// serving.
+func (m *Mig) OnRestart(h RestartHook) {
+	m.hooks.restart = append(m.hooks.restart, h)
+}
+
+type lifecycleHooks struct {
+	start, listen, shutdown, stopped []Hook
+	restart                          []RestartHook
+	// stoppedAfter holds the number of OnStart hooks registered before each
+	// OnStopped hook.
+	stoppedAfter []int
+	// started is the number of OnStart hooks that succeeded in Start.
+	started int
+}
+
+// hookTimeout is the time limit for a single hook: HookTimeout, but never
+// more than ShutdownTimeout.
+func (m *Mig) hookTimeout() time.Duration {
+	if m.HookTimeout > 0 && m.HookTimeout < m.ShutdownTimeout {
+		return m.HookTimeout
+	}
+	return m.ShutdownTimeout
+}
+
+// runStartHooks runs the OnStart hooks, stopping at the first error.
+func (m *Mig) runStartHooks() error {
+	m.hooks.started = 0
+	for i, h := range m.hooks.start {
+		if err := m.runHook(m.ctx, h); err != nil {
+			return fmt.Errorf("start hook #%d: %w", i, err)
+		}
+		m.hooks.started++
+	}
+	return nil
+}
+
+// startedStoppedHooks returns the OnStopped hooks registered before the
+// first OnStart hook that did not succeed.
+func (m *Mig) startedStoppedHooks() []Hook {
+	var hooks []Hook
+	for i, h := range m.hooks.stopped {
+		if m.hooks.stoppedAfter[i] <= m.hooks.started {
+			hooks = append(hooks, h)
+		}
+	}
+	return hooks
+}
+
+func (m *Mig) runHook(parent context.Context, h Hook) error {
+	ctx, cancel := context.WithTimeout(parent, m.hookTimeout())
+	defer cancel()
+	return h(ctx)
+}
+
+// runHooks runs hooks in order (or reverse order). With stopOnError it
+// returns the first error; otherwise it runs all hooks and joins the errors.
+func (m *Mig) runHooks(parent context.Context, stage string, hooks []Hook, reverse, stopOnError bool) error {
+	var errs []error
+	for i := range hooks {
+		idx := i
+		if reverse {
+			idx = len(hooks) - 1 - i
+		}
+		if err := m.runHook(parent, hooks[idx]); err != nil {
+			err = fmt.Errorf("%s hook #%d: %w", stage, idx, err)
+			if stopOnError {
+				return err
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
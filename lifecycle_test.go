@@ -0,0 +1,131 @@
+package mig_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/levmv/mig"
+)
+
+func TestLifecycleHooks(t *testing.T) {
+	m := mig.New(context.Background())
+	restoreLogger := setupSilentLogger(m)
+	defer restoreLogger()
+
+	var mu sync.Mutex
+	var events []string
+	record := func(name string, err error) mig.Hook {
+		return func(ctx context.Context) error {
+			mu.Lock()
+			events = append(events, name)
+			mu.Unlock()
+			return err
+		}
+	}
+
+	requestStarted := make(chan struct{})
+	m.GET("/slow", func(c *mig.Context) error {
+		close(requestStarted)
+		time.Sleep(50 * time.Millisecond)
+		mu.Lock()
+		events = append(events, "request done")
+		mu.Unlock()
+		return c.Raw([]byte("OK"))
+	})
+
+	m.OnStart(record("start db", nil))
+	m.OnStart(record("start cache", nil))
+	m.OnListen(record("listen", nil))
+	m.OnShutdown(record("shutdown db", nil))
+	m.OnShutdown(record("shutdown cache", errors.New("cache busy")))
+	m.OnStopped(record("close db", errors.New("db close failed")))
+	m.OnStopped(record("close cache", nil))
+
+	ln := mustListen(t)
+	addr := ln.Addr().String()
+	ln.Close()
+	if err := m.Start(addr); err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		res, err := http.Get("http://" + addr + "/slow")
+		if err == nil {
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+	}()
+	<-requestStarted
+
+	err := m.Shutdown()
+	if err == nil || !strings.Contains(err.Error(), "cache busy") || !strings.Contains(err.Error(), "db close failed") {
+		t.Fatalf("expected joined hook errors, got %v", err)
+	}
+	assertEqual(t, err, m.Shutdown(), "Repeated Shutdown returns the first result")
+
+	expected := "start db, start cache, listen, shutdown cache, shutdown db, request done, close cache, close db"
+	assertEqual(t, expected, strings.Join(events, ", "), "Hook order")
+}
+
+func TestLifecycleHooks_StartFailure(t *testing.T) {
+	m := mig.New(context.Background())
+	restoreLogger := setupSilentLogger(m)
+	defer restoreLogger()
+
+	var events []string
+	record := func(name string, err error) mig.Hook {
+		return func(ctx context.Context) error {
+			events = append(events, name)
+			return err
+		}
+	}
+	m.OnStart(record("open db", nil))
+	m.OnStopped(record("close db", nil))
+	m.OnStart(record("open cache", errors.New("cache unavailable")))
+	m.OnStopped(record("close cache", nil))
+	m.OnStart(record("open queue", nil))
+	m.OnStopped(record("close queue", nil))
+
+	err := m.Start("127.0.0.1:0")
+	if err == nil || !strings.Contains(err.Error(), "cache unavailable") {
+		t.Fatalf("expected start hook error, got %v", err)
+	}
+	assertEqual(t, "open db, open cache, close db", strings.Join(events, ", "), "Only hooks of started resources run")
+}
+
+func TestLifecycleHooks_Timeout(t *testing.T) {
+	m := mig.New(context.Background())
+	restoreLogger := setupSilentLogger(m)
+	defer restoreLogger()
+
+	m.HookTimeout = 10 * time.Millisecond
+	m.OnStopped(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	if err := m.Start("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	err := m.Shutdown()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected hook deadline error, got %v", err)
+	}
+}
+
+// mustListen reserves a free local TCP port.
+func mustListen(t *testing.T) net.Listener {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ln
+}
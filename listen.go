@@ -0,0 +1,208 @@
+package mig
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"os/user"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// UnixSocket describes a unix domain socket to listen on.
+type UnixSocket struct {
+	Path string
+	// Mode sets the socket file permissions, e.g. 0o660. Zero leaves them
+	// as created (subject to the umask).
+	Mode os.FileMode
+	// User and Group set the socket file owner, by name or numeric ID.
+	// Empty leaves the owner unchanged.
+	User  string
+	Group string
+}
+
+// Listen binds a listener on the given network ("tcp", "tcp4", "tcp6" or
+// "unix") and address, and adds it to the listeners served by Start.
+// It returns the bound address, which is useful with port 0.
+func (m *Mig) Listen(network, addr string) (net.Addr, error) {
+	ln := m.takeInherited(network, addr)
+	if ln == nil {
+		var err error
+		if ln, err = net.Listen(network, addr); err != nil {
+			return nil, err
+		}
+	}
+	m.AddListener(ln)
+	return ln.Addr(), nil
+}
+
+// ListenUnix binds a unix domain socket and adds it to the listeners served
+// by Start. A stale socket file left at the path is removed first. The socket
+// file is removed when the server shuts down.
+func (m *Mig) ListenUnix(sock UnixSocket) (net.Addr, error) {
+	if ln := m.takeInherited("unix", sock.Path); ln != nil {
+		m.AddListener(ln)
+		return ln.Addr(), nil
+	}
+	if fi, err := os.Lstat(sock.Path); err == nil {
+		if fi.Mode().Type() != fs.ModeSocket {
+			return nil, fmt.Errorf("mig: %s exists and is not a socket", sock.Path)
+		}
+		if err := os.Remove(sock.Path); err != nil {
+			return nil, err
+		}
+	}
+
+	ln, err := net.Listen("unix", sock.Path)
+	if err != nil {
+		return nil, err
+	}
+	if err := setSocketOwnerAndMode(sock); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	m.AddListener(ln)
+	return ln.Addr(), nil
+}
+
+func setSocketOwnerAndMode(sock UnixSocket) error {
+	if sock.User != "" || sock.Group != "" {
+		uid, gid := -1, -1
+		if sock.User != "" {
+			id, err := lookupID(sock.User, func(name string) (string, error) {
+				u, err := user.Lookup(name)
+				if err != nil {
+					return "", err
+				}
+				return u.Uid, nil
+			})
+			if err != nil {
+				return err
+			}
+			uid = id
+		}
+		if sock.Group != "" {
+			id, err := lookupID(sock.Group, func(name string) (string, error) {
+				g, err := user.LookupGroup(name)
+				if err != nil {
+					return "", err
+				}
+				return g.Gid, nil
+			})
+			if err != nil {
+				return err
+			}
+			gid = id
+		}
+		if err := os.Lchown(sock.Path, uid, gid); err != nil {
+			return err
+		}
+	}
+	if sock.Mode != 0 {
+		return os.Chmod(sock.Path, sock.Mode)
+	}
+	return nil
+}
+
+// lookupID resolves a numeric ID or a name.
+func lookupID(nameOrID string, lookup func(string) (string, error)) (int, error) {
+	if id, err := strconv.Atoi(nameOrID); err == nil {
+		return id, nil
+	}
+	s, err := lookup(nameOrID)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(s)
+}
+
+// AddListener adds a caller-provided listener to the listeners served by
+// Start. The server takes ownership of it and closes it on Shutdown.
+func (m *Mig) AddListener(ln net.Listener) {
+	m.lnMu.Lock()
+	m.listeners = append(m.listeners, ln)
+	m.lnMu.Unlock()
+}
+
+// Addrs returns the addresses of all listeners, in the order they were added.
+func (m *Mig) Addrs() []net.Addr {
+	m.lnMu.Lock()
+	defer m.lnMu.Unlock()
+	addrs := make([]net.Addr, len(m.listeners))
+	for i, ln := range m.listeners {
+		addrs[i] = ln.Addr()
+	}
+	return addrs
+}
+
+// Addr returns the address of the first listener, or nil if there is none.
+// After Start(":0") it reports the port actually bound.
+func (m *Mig) Addr() net.Addr {
+	m.lnMu.Lock()
+	defer m.lnMu.Unlock()
+	if len(m.listeners) == 0 {
+		return nil
+	}
+	return m.listeners[0].Addr()
+}
+
+// takeInherited returns the listener inherited from the parent process (see
+// Restart) that is bound to the given address, if there is one.
+func (m *Mig) takeInherited(network, addr string) net.Listener {
+	m.loadInherited()
+	m.lnMu.Lock()
+	defer m.lnMu.Unlock()
+	for i, ln := range m.inherited {
+		if sameAddr(ln.Addr(), network, addr) {
+			m.inherited = slices.Delete(m.inherited, i, i+1)
+			return ln
+		}
+	}
+	return nil
+}
+
+// adoptInherited serves the inherited listeners that were not claimed by
+// Listen or ListenUnix, e.g. ones added with AddListener in the parent.
+func (m *Mig) adoptInherited() {
+	m.loadInherited()
+	m.lnMu.Lock()
+	m.listeners = append(m.listeners, m.inherited...)
+	m.inherited = nil
+	m.lnMu.Unlock()
+}
+
+func sameAddr(a net.Addr, network, addr string) bool {
+	switch a := a.(type) {
+	case *net.UnixAddr:
+		return network == "unix" && a.Name == addr
+	case *net.TCPAddr:
+		if !strings.HasPrefix(network, "tcp") {
+			return false
+		}
+		want, err := net.ResolveTCPAddr(network, addr)
+		if err != nil || want.Port == 0 || want.Port != a.Port {
+			return false
+		}
+		if want.IP == nil || want.IP.IsUnspecified() {
+			return a.IP.IsUnspecified()
+		}
+		return want.IP.Equal(a.IP)
+	}
+	return false
+}
+
+var errNoListeners = errors.New("mig: no listeners; pass an address to Start or call Listen first")
+
+// closeListeners closes and forgets all listeners when Start fails, so that
+// a retried Start binds new ones.
+func (m *Mig) closeListeners() {
+	m.lnMu.Lock()
+	defer m.lnMu.Unlock()
+	for _, ln := range m.listeners {
+		ln.Close()
+	}
+	m.listeners = nil
+}
@@ -0,0 +1,167 @@
+package mig_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/levmv/mig"
+)
+
+func get(t *testing.T, client *http.Client, url string) string {
+	t.Helper()
+	res, err := client.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	assertNoError(t, err, "Failed to read response body")
+	return string(body)
+}
+
+func TestMultipleListeners(t *testing.T) {
+	m := mig.New(context.Background())
+	restoreLogger := setupSilentLogger(m)
+	defer restoreLogger()
+
+	m.GET("/", func(c *mig.Context) error { return c.Raw([]byte("OK")) })
+
+	sockPath := filepath.Join(t.TempDir(), "mig.sock")
+	_, err := m.ListenUnix(mig.UnixSocket{Path: sockPath, Mode: 0o600})
+	assertNoError(t, err, "ListenUnix failed")
+
+	custom, err := net.Listen("tcp", "127.0.0.1:0")
+	assertNoError(t, err, "net.Listen failed")
+	m.AddListener(custom)
+
+	assertNoError(t, m.Start("127.0.0.1:0"), "Start failed")
+
+	addrs := m.Addrs()
+	assertEqual(t, 3, len(addrs), "Listener count")
+	assertEqual(t, sockPath, m.Addr().String(), "First listener is the unix socket")
+
+	tcpAddr := addrs[2].(*net.TCPAddr)
+	if tcpAddr.Port == 0 {
+		t.Fatal("Addrs should report the bound port, not 0")
+	}
+
+	fi, err := os.Stat(sockPath)
+	assertNoError(t, err, "Socket file missing")
+	assertEqual(t, os.FileMode(0o600), fi.Mode().Perm(), "Socket file mode")
+
+	unixClient := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", sockPath)
+		},
+	}}
+	assertEqual(t, "OK", get(t, unixClient, "http://unix/"), "Unix socket response")
+	assertEqual(t, "OK", get(t, http.DefaultClient, "http://"+custom.Addr().String()+"/"), "Custom listener response")
+	assertEqual(t, "OK", get(t, http.DefaultClient, "http://"+tcpAddr.String()+"/"), "TCP listener response")
+
+	assertNoError(t, m.Shutdown(), "Shutdown failed")
+
+	if _, err := os.Stat(sockPath); !os.IsNotExist(err) {
+		t.Errorf("socket file should be removed on shutdown, stat err: %v", err)
+	}
+	if _, err := net.Dial("tcp", tcpAddr.String()); err == nil {
+		t.Error("TCP listener should be closed after shutdown")
+	}
+}
+
+func TestStartWithoutListeners(t *testing.T) {
+	m := mig.New(context.Background())
+	restoreLogger := setupSilentLogger(m)
+	defer restoreLogger()
+
+	if err := m.Start(""); err == nil {
+		t.Fatal("Start without address or listeners should fail")
+	}
+}
+
+func TestStartRetryAfterFailure(t *testing.T) {
+	m := mig.New(context.Background())
+	restoreLogger := setupSilentLogger(m)
+	defer restoreLogger()
+
+	m.GET("/", func(c *mig.Context) error { return c.Raw([]byte("OK")) })
+	failed := false
+	m.OnStart(func(ctx context.Context) error {
+		if !failed {
+			failed = true
+			return errors.New("not ready")
+		}
+		return nil
+	})
+
+	if _, err := m.Listen("tcp", "127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Start(""); err == nil {
+		t.Fatal("first Start should fail")
+	}
+	if err := m.Start("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Shutdown()
+
+	assertEqual(t, 1, len(m.Addrs()), "Closed listeners are forgotten")
+	assertEqual(t, "OK", get(t, http.DefaultClient, "http://"+m.Addr().String()+"/"), "Retried Start serves")
+}
+
+func TestListenUnix_RefusesToRemoveRegularFile(t *testing.T) {
+	m := mig.New(context.Background())
+	path := filepath.Join(t.TempDir(), "not-a-socket")
+	assertNoError(t, os.WriteFile(path, []byte("data"), 0o600), "WriteFile failed")
+
+	if _, err := m.ListenUnix(mig.UnixSocket{Path: path}); err == nil {
+		t.Fatal("ListenUnix should refuse to replace a regular file")
+	}
+	_, err := os.Stat(path)
+	assertNoError(t, err, "Regular file must be left in place")
+}
+
+func TestMaxConnections(t *testing.T) {
+	m := mig.New(context.Background())
+	restoreLogger := setupSilentLogger(m)
+	defer restoreLogger()
+
+	m.MaxConnections = 1
+	m.GET("/", func(c *mig.Context) error { return c.Raw([]byte("OK")) })
+	assertNoError(t, m.Start("127.0.0.1:0"), "Start failed")
+	defer m.Shutdown()
+
+	request := func(conn net.Conn) error {
+		io.WriteString(conn, "GET / HTTP/1.1\r\nHost: x\r\n\r\n")
+		res, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		if err == nil {
+			res.Body.Close()
+		}
+		return err
+	}
+
+	first, err := net.Dial("tcp", m.Addr().String())
+	assertNoError(t, err, "Dial failed")
+	assertNoError(t, request(first), "First connection")
+
+	second, err := net.Dial("tcp", m.Addr().String())
+	assertNoError(t, err, "Dial failed")
+	defer second.Close()
+	second.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	if err := request(second); err == nil {
+		t.Fatal("second connection should wait while the first is open")
+	}
+
+	first.Close()
+	second.SetReadDeadline(time.Now().Add(time.Second))
+	res, err := http.ReadResponse(bufio.NewReader(second), nil)
+	assertNoError(t, err, "Second connection served after the first closed")
+	res.Body.Close()
+}
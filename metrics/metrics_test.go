@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/levmv/mig"
+)
+
+func assertEqual(t *testing.T, expected, actual any, msg string) {
+	t.Helper()
+	if expected != actual {
+		t.Fatalf("%s: mismatch\n\texp: %v\n\tgot: %v", msg, expected, actual)
+	}
+}
+
+func TestRegistry_WriteTo(t *testing.T) {
+	reg := NewRegistry()
+
+	jobs := reg.Counter("jobs_total", "Jobs processed.", "queue")
+	jobs.Inc("mail")
+	jobs.Add(2, "mail")
+	jobs.Inc(`we"ird`)
+
+	temp := reg.Gauge("temperature", "Current temperature.\nIn celsius.")
+	temp.Set(21.5)
+	temp.Dec()
+
+	lat := reg.Histogram("latency_seconds", "", []float64{0.1, 1})
+	lat.Observe(0.05)
+	lat.Observe(0.1)
+	lat.Observe(0.5)
+	lat.Observe(3)
+
+	var sb strings.Builder
+	_, err := reg.WriteTo(&sb)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `# HELP jobs_total Jobs processed.
+# TYPE jobs_total counter
+jobs_total{queue="mail"} 3
+jobs_total{queue="we\"ird"} 1
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 2
+latency_seconds_bucket{le="1"} 3
+latency_seconds_bucket{le="+Inf"} 4
+latency_seconds_sum 3.65
+latency_seconds_count 4
+# HELP temperature Current temperature.\nIn celsius.
+# TYPE temperature gauge
+temperature 20.5
+`
+	assertEqual(t, expected, sb.String(), "Exposition mismatch")
+}
+
+func TestRegistry_ConflictingRegistrationPanics(t *testing.T) {
+	reg := NewRegistry()
+	reg.Counter("x", "", "a")
+	reg.Counter("x", "", "a") // identical registration returns the existing counter
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("registering x as a gauge should have panicked")
+		}
+	}()
+	reg.Gauge("x", "")
+}
+
+func TestMiddleware(t *testing.T) {
+	m := mig.New(context.Background())
+	m.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	reg := NewRegistry()
+	m.Use(MiddlewareWithConfig(MiddlewareConfig{Registry: reg, Buckets: []float64{10}}))
+
+	m.GET("/users/{id}", func(c *mig.Context) error { return c.Raw([]byte("OK")) })
+	m.GET("/missing", func(c *mig.Context) error { return mig.ErrNotFound })
+	m.GET("/fail", func(c *mig.Context) error { return errors.New("boom") })
+	m.GET("/panic", func(c *mig.Context) error { panic("boom") })
+	m.GET("/metrics", Handler(reg))
+
+	for _, path := range []string{"/users/1", "/users/2", "/missing", "/fail", "/panic"} {
+		m.Mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	m.Mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+
+	assertEqual(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"), "Content-Type mismatch")
+	for _, line := range []string{
+		`http_requests_total{method="GET",route="/users/{id}",status="2xx"} 2`,
+		`http_requests_total{method="GET",route="/missing",status="4xx"} 1`,
+		`http_requests_total{method="GET",route="/fail",status="5xx"} 1`,
+		`http_requests_total{method="GET",route="/panic",status="5xx"} 1`,
+		`http_request_duration_seconds_count{method="GET",route="/users/{id}",status="2xx"} 2`,
+		`http_requests_in_flight 1`, // the /metrics request itself
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("metrics output is missing %q:\n%s", line, body)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/levmv/mig"
+)
+
+// MiddlewareConfig configures the request metrics middleware.
+type MiddlewareConfig struct {
+	// Registry receives the metrics. Default is DefaultRegistry.
+	Registry *Registry
+	// Namespace is prepended to metric names, e.g. "myapp" gives
+	// "myapp_http_requests_total".
+	Namespace string
+	// Buckets for the request duration histogram. Default is DefBuckets.
+	Buckets []float64
+}
+
+// Middleware records request metrics into DefaultRegistry.
+func Middleware() mig.MiddlewareFunc {
+	return MiddlewareWithConfig(MiddlewareConfig{})
+}
+
+// MiddlewareWithConfig returns a middleware recording:
+//
+//   - http_requests_total{method,route,status}: counter of finished requests
+//   - http_request_duration_seconds{method,route,status}: latency histogram
+//   - http_requests_in_flight: gauge of requests being served
+//
+// The route label is the pattern the route was registered with (e.g.
+// "/users/{id}"), not the raw path, so the number of series stays bounded.
+// Status is recorded as a class: "2xx", "4xx", etc.
+func MiddlewareWithConfig(cfg MiddlewareConfig) mig.MiddlewareFunc {
+	reg := cfg.Registry
+	if reg == nil {
+		reg = DefaultRegistry
+	}
+	prefix := ""
+	if cfg.Namespace != "" {
+		prefix = cfg.Namespace + "_"
+	}
+
+	requests := reg.Counter(prefix+"http_requests_total",
+		"Total number of HTTP requests.", "method", "route", "status")
+	duration := reg.Histogram(prefix+"http_request_duration_seconds",
+		"HTTP request latency in seconds.", cfg.Buckets, "method", "route", "status")
+	inFlight := reg.Gauge(prefix+"http_requests_in_flight",
+		"Number of HTTP requests currently being served.")
+
+	return func(next mig.Handler) mig.Handler {
+		return func(c *mig.Context) (err error) {
+			start := time.Now()
+			inFlight.Inc()
+
+			panicked := true
+			defer func() {
+				inFlight.Dec()
+
+				status := http.StatusInternalServerError
+				if !panicked {
+					status = c.ResponseStatus(err)
+				}
+				method := methodLabel(c.Request.Method)
+				route := routeLabel(c)
+				class := statusClass(status)
+
+				requests.Inc(method, route, class)
+				duration.Observe(time.Since(start).Seconds(), method, route, class)
+			}()
+
+			err = next(c)
+			panicked = false
+			return err
+		}
+	}
+}
+
+// methodLabel limits the method label to standard methods, since clients can
+// send arbitrary ones.
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
+
+func routeLabel(c *mig.Context) string {
+	if r := c.Route(); r != nil {
+		return r.Pattern
+	}
+	return "unmatched"
+}
+
+func statusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "other"
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// Handler returns a handler exposing the registry in the Prometheus text format.
+func Handler(reg *Registry) mig.Handler {
+	return func(c *mig.Context) error {
+		c.Response.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, err := reg.WriteTo(c.Response)
+		return err
+	}
+}
@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/levmv/mig"
+)
+
+// APIKeyHeader is the default header APIKey reads the key from.
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyConfig configures the APIKey middleware.
+// The key is looked up in Header, then QueryParam, then Cookie; empty sources are skipped.
+type APIKeyConfig struct {
+	// Lookup resolves a key to a principal. The principal is stored on the
+	// Context and is available through Context.Principal(); return an
+	// authz.Principal to use it with the authz package.
+	Lookup func(key string) (principal any, ok bool)
+	// Header is the request header carrying the key. Defaults to X-API-Key
+	// unless QueryParam or Cookie is set.
+	Header     string
+	QueryParam string
+	Cookie     string
+}
+
+// APIKey returns a middleware that authenticates requests by the X-API-Key header.
+func APIKey(lookup func(key string) (principal any, ok bool)) mig.MiddlewareFunc {
+	return APIKeyWithConfig(APIKeyConfig{Lookup: lookup})
+}
+
+func APIKeyWithConfig(cfg APIKeyConfig) mig.MiddlewareFunc {
+	if cfg.Lookup == nil {
+		panic("APIKey middleware requires Lookup function")
+	}
+	if cfg.Header == "" && cfg.QueryParam == "" && cfg.Cookie == "" {
+		cfg.Header = APIKeyHeader
+	}
+
+	return func(next mig.Handler) mig.Handler {
+		return func(c *mig.Context) error {
+			key := extractAPIKey(c, &cfg)
+			if key != "" {
+				if principal, ok := cfg.Lookup(key); ok {
+					c.SetPrincipal(principal)
+					return next(c)
+				}
+			}
+			return mig.NewHTTPError(http.StatusUnauthorized)
+		}
+	}
+}
+
+func extractAPIKey(c *mig.Context, cfg *APIKeyConfig) string {
+	if cfg.Header != "" {
+		if key := c.Request.Header.Get(cfg.Header); key != "" {
+			return key
+		}
+	}
+	if cfg.QueryParam != "" {
+		if key := c.QueryParam(cfg.QueryParam, ""); key != "" {
+			return key
+		}
+	}
+	if cfg.Cookie != "" {
+		if cookie, err := c.Request.Cookie(cfg.Cookie); err == nil {
+			return cookie.Value
+		}
+	}
+	return ""
+}
+
+// HashAPIKey returns the hex-encoded SHA-256 hash of key, the form in which
+// keys are registered in an APIKeySet.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeySet is a set of API keys kept only as SHA-256 hashes, so plaintext keys
+// never have to be held in memory. It is safe for concurrent use.
+//
+// Usage:
+//
+//	keys := middleware.NewAPIKeySet()
+//	keys.Add(os.Getenv("BILLING_KEY_SHA256"), "billing-service", "internal")
+//	internal := m.Group("/internal", middleware.APIKey(keys.Scoped("internal")))
+type APIKeySet struct {
+	mu      sync.RWMutex
+	entries []apiKeyEntry
+}
+
+type apiKeyEntry struct {
+	hash      [sha256.Size]byte
+	principal any
+	scopes    []string
+}
+
+func NewAPIKeySet() *APIKeySet {
+	return &APIKeySet{}
+}
+
+// Add registers a key by its hex-encoded SHA-256 hash (see HashAPIKey).
+// If scopes are given, the key is accepted only by lookups returned from
+// Scoped for one of those scopes; a key without scopes is accepted everywhere.
+// Add panics if hash is not a valid hex-encoded SHA-256 sum.
+func (s *APIKeySet) Add(hash string, principal any, scopes ...string) {
+	var e apiKeyEntry
+	b, err := hex.DecodeString(hash)
+	if err != nil || len(b) != sha256.Size {
+		panic("APIKeySet: key hash must be a hex-encoded SHA-256 sum")
+	}
+	copy(e.hash[:], b)
+	e.principal = principal
+	e.scopes = scopes
+
+	s.mu.Lock()
+	s.entries = append(s.entries, e)
+	s.mu.Unlock()
+}
+
+// Lookup resolves a plaintext key to its principal, ignoring scopes.
+func (s *APIKeySet) Lookup(key string) (any, bool) {
+	return s.lookup(key, "")
+}
+
+// Scoped returns a lookup function that accepts only keys registered without
+// scopes or with the given scope. Use one per route group.
+func (s *APIKeySet) Scoped(scope string) func(key string) (any, bool) {
+	return func(key string) (any, bool) {
+		return s.lookup(key, scope)
+	}
+}
+
+func (s *APIKeySet) lookup(key, scope string) (any, bool) {
+	sum := sha256.Sum256([]byte(key))
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Compare against every entry so the time taken does not depend on
+	// where (or whether) the key is found.
+	var found *apiKeyEntry
+	for i := range s.entries {
+		if subtle.ConstantTimeCompare(sum[:], s.entries[i].hash[:]) == 1 && found == nil {
+			found = &s.entries[i]
+		}
+	}
+	if found == nil {
+		return nil, false
+	}
+	if scope != "" && len(found.scopes) > 0 && !slices.Contains(found.scopes, scope) {
+		return nil, false
+	}
+	return found.principal, true
+}
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/levmv/mig"
+)
+
+func TestAPIKey(t *testing.T) {
+	m := mig.New(context.Background())
+
+	restoreLogger := setupSilentLogger(m)
+	defer restoreLogger()
+
+	keys := NewAPIKeySet()
+	keys.Add(HashAPIKey("global-key"), "global")
+	keys.Add(HashAPIKey("admin-key"), "admin", "admin")
+	keys.Add(HashAPIKey("billing-key"), "billing", "billing")
+
+	handler := func(c *mig.Context) error {
+		return c.Raw([]byte(c.Principal().(string)))
+	}
+
+	admin := m.Group("/admin", APIKeyWithConfig(APIKeyConfig{
+		Lookup:     keys.Scoped("admin"),
+		Header:     APIKeyHeader,
+		QueryParam: "api_key",
+		Cookie:     "api_key",
+	}))
+	admin.GET("/status", handler)
+
+	testCases := []struct {
+		name         string
+		setup        func(r *http.Request)
+		target       string
+		expectStatus int
+		expectBody   string
+	}{
+		{
+			name:         "Key in header",
+			setup:        func(r *http.Request) { r.Header.Set(APIKeyHeader, "admin-key") },
+			target:       "/admin/status",
+			expectStatus: http.StatusOK,
+			expectBody:   "admin",
+		},
+		{
+			name:         "Key in query",
+			target:       "/admin/status?api_key=admin-key",
+			expectStatus: http.StatusOK,
+			expectBody:   "admin",
+		},
+		{
+			name:         "Key in cookie",
+			setup:        func(r *http.Request) { r.AddCookie(&http.Cookie{Name: "api_key", Value: "admin-key"}) },
+			target:       "/admin/status",
+			expectStatus: http.StatusOK,
+			expectBody:   "admin",
+		},
+		{
+			name:         "Unscoped key is accepted everywhere",
+			setup:        func(r *http.Request) { r.Header.Set(APIKeyHeader, "global-key") },
+			target:       "/admin/status",
+			expectStatus: http.StatusOK,
+			expectBody:   "global",
+		},
+		{
+			name:         "Key scoped to another group",
+			setup:        func(r *http.Request) { r.Header.Set(APIKeyHeader, "billing-key") },
+			target:       "/admin/status",
+			expectStatus: http.StatusUnauthorized,
+			expectBody:   "Unauthorized\n",
+		},
+		{
+			name:         "Unknown key",
+			setup:        func(r *http.Request) { r.Header.Set(APIKeyHeader, "nope") },
+			target:       "/admin/status",
+			expectStatus: http.StatusUnauthorized,
+			expectBody:   "Unauthorized\n",
+		},
+		{
+			name:         "No key",
+			target:       "/admin/status",
+			expectStatus: http.StatusUnauthorized,
+			expectBody:   "Unauthorized\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			if tc.setup != nil {
+				tc.setup(req)
+			}
+			rec := httptest.NewRecorder()
+
+			m.Mux.ServeHTTP(rec, req)
+
+			assertEqual(t, tc.expectStatus, rec.Code, "Status code mismatch")
+			assertEqual(t, tc.expectBody, rec.Body.String(), "Response body mismatch")
+		})
+	}
+}
+
+func TestAPIKeySet_AddPanicsOnPlaintext(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Add should have panicked on a non-hash key, but did not")
+		}
+	}()
+
+	NewAPIKeySet().Add("plaintext-key", "svc")
+}
@@ -3,13 +3,22 @@ package middleware
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/levmv/mig"
+	"github.com/levmv/mig/authz"
 )
 
 type BasicAuthConfig struct {
 	IsAllowed func(user string, password string) bool
 	Realm     string
+	// FailureDelay is waited before answering a request with wrong credentials,
+	// to slow down brute-force attempts. Requests without credentials are not delayed.
+	FailureDelay time.Duration
+	// Principal returns the principal stored on the Context for an
+	// authenticated user, e.g. with the user's roles loaded. Default is
+	// &authz.User{Subject: user}.
+	Principal func(user string) authz.Principal
 }
 
 func BasicAuthWithConfig(cfg BasicAuthConfig) mig.MiddlewareFunc {
@@ -22,16 +31,32 @@ func BasicAuthWithConfig(cfg BasicAuthConfig) mig.MiddlewareFunc {
 	} else {
 		realm = strconv.Quote(cfg.Realm)
 	}
+	if cfg.Principal == nil {
+		cfg.Principal = defaultPrincipal
+	}
 
 	return func(next mig.Handler) mig.Handler {
 		return func(m *mig.Context) error {
 			u, p, ok := m.Request.BasicAuth()
 			if ok && cfg.IsAllowed(u, p) {
+				m.SetPrincipal(cfg.Principal(u))
 				return next(m)
 			}
+			if ok && cfg.FailureDelay > 0 {
+				t := time.NewTimer(cfg.FailureDelay)
+				select {
+				case <-t.C:
+				case <-m.Request.Context().Done():
+					t.Stop()
+				}
+			}
 
 			m.Response.Header().Set("WWW-Authenticate", "Basic realm="+realm)
 			return mig.NewHTTPError(http.StatusUnauthorized)
 		}
 	}
 }
+
+func defaultPrincipal(user string) authz.Principal {
+	return &authz.User{Subject: user}
+}
@@ -0,0 +1,265 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/levmv/mig"
+)
+
+// BodyDumpRecord is a captured request/response exchange.
+type BodyDumpRecord struct {
+	Method            string
+	URL               string
+	RequestHeader     http.Header
+	RequestBody       []byte
+	RequestTruncated  bool
+	Status            int
+	ResponseHeader    http.Header
+	ResponseBody      []byte
+	ResponseTruncated bool
+	Duration          time.Duration
+}
+
+// BodyDumpConfig configures the BodyDump middleware.
+type BodyDumpConfig struct {
+	// MaxBodySize limits how many bytes of each body are captured. Default is 64 KiB.
+	MaxBodySize int
+	// RedactHeaders are dumped as "[REDACTED]". Default is Authorization,
+	// Proxy-Authorization, Cookie, Set-Cookie and X-API-Key.
+	RedactHeaders []string
+	// RedactFields are JSON object keys, form fields and query parameters
+	// whose values are redacted, matched case-insensitively at any depth.
+	// Default is password and api_key.
+	RedactFields []string
+	// Sink receives each dump. Default logs it to c.Logger at Info level.
+	Sink func(c *mig.Context, d *BodyDumpRecord)
+	// Skip prevents dumping of matching requests.
+	Skip func(c *mig.Context) bool
+	// TriggerHeader and TriggerSecret, if set, restrict dumping to requests
+	// carrying TriggerHeader with the value TriggerSecret. The header is
+	// removed before the request reaches the handler.
+	TriggerHeader string
+	TriggerSecret string
+}
+
+const redacted = "[REDACTED]"
+
+// BodyDump returns a debugging middleware that captures request and response
+// headers and bodies. Apply it to a single route or group, or enable it
+// per request with TriggerHeader/TriggerSecret.
+//
+// To capture error responses, errors returned by the handler are passed to
+// Mig.ErrorHandler inside this middleware, so outer middleware sees them as
+// already written responses.
+func BodyDump(cfg BodyDumpConfig) mig.MiddlewareFunc {
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = 64 << 10
+	}
+	if cfg.RedactHeaders == nil {
+		cfg.RedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-API-Key"}
+	}
+	if cfg.RedactFields == nil {
+		cfg.RedactFields = []string{"password", "api_key"}
+	}
+	if cfg.Sink == nil {
+		cfg.Sink = logBodyDump
+	}
+	fieldRe := redactFieldsRegexp(cfg.RedactFields)
+
+	return func(next mig.Handler) mig.Handler {
+		return func(c *mig.Context) error {
+			if cfg.TriggerHeader != "" {
+				v := c.Request.Header.Get(cfg.TriggerHeader)
+				c.Request.Header.Del(cfg.TriggerHeader)
+				if v == "" || subtle.ConstantTimeCompare([]byte(v), []byte(cfg.TriggerSecret)) != 1 {
+					return next(c)
+				}
+			}
+			if cfg.Skip != nil && cfg.Skip(c) {
+				return next(c)
+			}
+
+			start := time.Now()
+			d := &BodyDumpRecord{
+				Method:        c.Request.Method,
+				URL:           redactURL(c.Request.URL, cfg.RedactFields),
+				RequestHeader: redactHeader(c.Request.Header, cfg.RedactHeaders),
+			}
+
+			if c.Request.Body != nil && c.Request.Body != http.NoBody {
+				body := c.Request.Body
+				buf, _ := io.ReadAll(io.LimitReader(body, int64(cfg.MaxBodySize)+1))
+				c.Request.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.MultiReader(bytes.NewReader(buf), body), body}
+
+				d.RequestTruncated = len(buf) > cfg.MaxBodySize
+				d.RequestBody = redactBody(buf[:min(len(buf), cfg.MaxBodySize)], c.Request.Header.Get("Content-Type"), cfg.RedactFields, fieldRe)
+			}
+
+			w := &dumpWriter{ResponseWriter: c.Response.ResponseWriter, limit: cfg.MaxBodySize}
+			c.Response.ResponseWriter = w
+
+			if err := next(c); err != nil {
+				c.Mig.ErrorHandler(err, c)
+			}
+
+			d.Status = c.Response.Status()
+			d.ResponseHeader = redactHeader(c.Response.Header(), cfg.RedactHeaders)
+			d.ResponseBody = redactBody(w.buf.Bytes(), c.Response.Header().Get("Content-Type"), cfg.RedactFields, fieldRe)
+			d.ResponseTruncated = w.truncated
+			d.Duration = time.Since(start)
+
+			cfg.Sink(c, d)
+			return nil
+		}
+	}
+}
+
+func logBodyDump(c *mig.Context, d *BodyDumpRecord) {
+	c.Logger.Info("body dump",
+		slog.String("method", d.Method),
+		slog.String("url", d.URL),
+		slog.Any("request_header", d.RequestHeader),
+		slog.String("request_body", string(d.RequestBody)),
+		slog.Bool("request_truncated", d.RequestTruncated),
+		slog.Int("status", d.Status),
+		slog.Any("response_header", d.ResponseHeader),
+		slog.String("response_body", string(d.ResponseBody)),
+		slog.Bool("response_truncated", d.ResponseTruncated),
+		slog.Duration("t", d.Duration),
+	)
+}
+
+// dumpWriter copies up to limit bytes of the response body.
+type dumpWriter struct {
+	http.ResponseWriter
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (w *dumpWriter) Write(b []byte) (int, error) {
+	if room := w.limit - w.buf.Len(); room > 0 {
+		w.buf.Write(b[:min(len(b), room)])
+		w.truncated = w.truncated || len(b) > room
+	} else if len(b) > 0 {
+		w.truncated = true
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *dumpWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func redactHeader(h http.Header, names []string) http.Header {
+	out := h.Clone()
+	for _, name := range names {
+		if _, ok := out[http.CanonicalHeaderKey(name)]; ok {
+			out.Set(name, redacted)
+		}
+	}
+	return out
+}
+
+// redactURL redacts query parameters named after one of fields, keeping the
+// rest of the query as sent.
+func redactURL(u *url.URL, fields []string) string {
+	if u.RawQuery == "" || len(fields) == 0 {
+		return u.String()
+	}
+	params := strings.Split(u.RawQuery, "&")
+	for i, param := range params {
+		key, _, _ := strings.Cut(param, "=")
+		if k, err := url.QueryUnescape(key); err == nil {
+			key = k
+		}
+		if containsFold(fields, key) {
+			params[i] = url.QueryEscape(key) + "=" + redacted
+		}
+	}
+	out := *u
+	out.RawQuery = strings.Join(params, "&")
+	return out.String()
+}
+
+// redactFieldsRegexp matches JSON string members named after one of fields.
+// It is the fallback for bodies that are truncated or otherwise not valid JSON.
+func redactFieldsRegexp(fields []string) *regexp.Regexp {
+	if len(fields) == 0 {
+		return nil
+	}
+	quoted := make([]string, len(fields))
+	for i, f := range fields {
+		quoted[i] = regexp.QuoteMeta(f)
+	}
+	return regexp.MustCompile(`(?i)("(?:` + strings.Join(quoted, "|") + `)"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]\s]+)`)
+}
+
+func redactBody(body []byte, contentType string, fields []string, fieldRe *regexp.Regexp) []byte {
+	if len(body) == 0 || len(fields) == 0 {
+		return body
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		var v any
+		if json.Unmarshal(body, &v) == nil {
+			if out, err := json.Marshal(redactJSON(v, fields)); err == nil {
+				return out
+			}
+		}
+		return fieldRe.ReplaceAll(body, []byte(`${1}"`+redacted+`"`))
+	case mediaType == "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return body
+		}
+		for key := range values {
+			for _, f := range fields {
+				if strings.EqualFold(key, f) {
+					values[key] = []string{redacted}
+				}
+			}
+		}
+		return []byte(values.Encode())
+	}
+	return body
+}
+
+func redactJSON(v any, fields []string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, val := range v {
+			matched := false
+			for _, f := range fields {
+				if strings.EqualFold(key, f) {
+					matched = true
+					break
+				}
+			}
+			if matched {
+				v[key] = redacted
+			} else {
+				v[key] = redactJSON(val, fields)
+			}
+		}
+	case []any:
+		for i := range v {
+			v[i] = redactJSON(v[i], fields)
+		}
+	}
+	return v
+}
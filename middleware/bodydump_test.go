@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/levmv/mig"
+)
+
+func TestBodyDump(t *testing.T) {
+	m := mig.New(context.Background())
+
+	restoreLogger := setupSilentLogger(m)
+	defer restoreLogger()
+
+	var dump *BodyDumpRecord
+	m.Use(BodyDump(BodyDumpConfig{
+		MaxBodySize:   64,
+		TriggerHeader: "X-Debug-Dump",
+		TriggerSecret: "s3cret",
+		Sink:          func(c *mig.Context, d *BodyDumpRecord) { dump = d },
+	}))
+
+	var received string
+	m.POST("/login", func(c *mig.Context) error {
+		b, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return err
+		}
+		received = string(b)
+		c.Response.Header().Set("Set-Cookie", "session=xyz")
+		c.Response.Header().Set("Content-Type", "application/json")
+		return c.Raw([]byte(`{"user":"mig","token":"abc","nested":{"Password":"p"}}`))
+	})
+	m.POST("/fail", func(c *mig.Context) error {
+		return mig.NewHTTPError(http.StatusTeapot)
+	})
+
+	t.Run("Captures and redacts", func(t *testing.T) {
+		body := `{"user":"mig","password":"hunter2"}`
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer secret-token")
+		req.Header.Set("X-Debug-Dump", "s3cret")
+		rec := httptest.NewRecorder()
+		m.Mux.ServeHTTP(rec, req)
+
+		assertEqual(t, body, received, "Handler must still see the full body")
+		if dump == nil {
+			t.Fatal("expected a dump")
+		}
+		assertEqual(t, `{"password":"[REDACTED]","user":"mig"}`, string(dump.RequestBody), "Request body redaction")
+		assertEqual(t, "[REDACTED]", dump.RequestHeader.Get("Authorization"), "Authorization redaction")
+		assertEqual(t, "", dump.RequestHeader.Get("X-Debug-Dump"), "Trigger header is removed")
+		assertEqual(t, "[REDACTED]", dump.ResponseHeader.Get("Set-Cookie"), "Set-Cookie redaction")
+		assertEqual(t, `{"nested":{"Password":"[REDACTED]"},"token":"abc","user":"mig"}`, string(dump.ResponseBody), "Response body redaction")
+		assertEqual(t, http.StatusOK, dump.Status, "Status")
+	})
+
+	t.Run("Query parameters are redacted", func(t *testing.T) {
+		dump = nil
+		req := httptest.NewRequest(http.MethodPost, "/login?user=mig&Password=hunter2&api_key=k%26y&x", strings.NewReader("{}"))
+		req.Header.Set("X-Debug-Dump", "s3cret")
+		m.Mux.ServeHTTP(httptest.NewRecorder(), req)
+
+		assertEqual(t, "/login?user=mig&Password=[REDACTED]&api_key=[REDACTED]&x", dump.URL, "Redacted URL")
+	})
+
+	t.Run("Truncated body falls back to pattern redaction", func(t *testing.T) {
+		dump = nil
+		body := `{"password":"hunter2","padding":"` + strings.Repeat("x", 100) + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Debug-Dump", "s3cret")
+		m.Mux.ServeHTTP(httptest.NewRecorder(), req)
+
+		assertEqual(t, body, received, "Handler must still see the full body")
+		assertEqual(t, true, dump.RequestTruncated, "Request should be truncated")
+		if strings.Contains(string(dump.RequestBody), "hunter2") {
+			t.Errorf("password leaked into dump: %s", dump.RequestBody)
+		}
+	})
+
+	t.Run("Error responses are captured", func(t *testing.T) {
+		dump = nil
+		req := httptest.NewRequest(http.MethodPost, "/fail", nil)
+		req.Header.Set("X-Debug-Dump", "s3cret")
+		rec := httptest.NewRecorder()
+		m.Mux.ServeHTTP(rec, req)
+
+		assertEqual(t, http.StatusTeapot, rec.Code, "Status code mismatch")
+		assertEqual(t, http.StatusTeapot, dump.Status, "Dumped status")
+		assertEqual(t, "I'm a teapot\n", string(dump.ResponseBody), "Dumped error body")
+	})
+
+	t.Run("Wrong secret disables dump", func(t *testing.T) {
+		dump = nil
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader("{}"))
+		req.Header.Set("X-Debug-Dump", "guess")
+		m.Mux.ServeHTTP(httptest.NewRecorder(), req)
+
+		if dump != nil {
+			t.Error("dump should not be produced without the right secret")
+		}
+	})
+}
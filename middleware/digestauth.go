@@ -0,0 +1,306 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/levmv/mig"
+	"github.com/levmv/mig/authz"
+)
+
+// Digest algorithms supported by DigestAuthWithConfig.
+const (
+	DigestSHA256 = "SHA-256"
+	DigestMD5    = "MD5"
+)
+
+type DigestAuthConfig struct {
+	// Password returns the password for user, or false if there is no such user.
+	Password func(user string) (password string, ok bool)
+	Realm    string
+	// Algorithms lists the offered algorithms in order of preference.
+	// Default is SHA-256 followed by MD5 for older clients.
+	Algorithms []string
+	// NonceTTL is how long a server nonce stays valid. Default is 5 minutes.
+	NonceTTL time.Duration
+	// MaxNonces limits how many nonces in use are remembered to detect
+	// replayed nonce counts. When full, the oldest nonce is forgotten and
+	// clients using it must reauthenticate. Default is 10000.
+	MaxNonces int
+	// Principal returns the principal stored on the Context for an
+	// authenticated user. Default is &authz.User{Subject: user}.
+	Principal func(user string) authz.Principal
+}
+
+// DigestAuthWithConfig returns a middleware implementing HTTP Digest
+// authentication (RFC 7616) with qop=auth. Nonces are signed by the server,
+// so issuing them keeps no state; they expire after NonceTTL and every request
+// must use a higher nonce count than the previous one, so captured requests
+// cannot be replayed.
+func DigestAuthWithConfig(cfg DigestAuthConfig) mig.MiddlewareFunc {
+	if cfg.Password == nil {
+		panic("DigestAuth middleware requires Password function")
+	}
+	realm := cfg.Realm
+	if realm == "" {
+		realm = "restricted"
+	}
+	quotedRealm := strconv.Quote(realm)
+	if len(cfg.Algorithms) == 0 {
+		cfg.Algorithms = []string{DigestSHA256, DigestMD5}
+	}
+	for _, alg := range cfg.Algorithms {
+		if digestHash(alg) == nil {
+			panic("DigestAuth middleware: unsupported algorithm " + alg)
+		}
+	}
+	if cfg.NonceTTL <= 0 {
+		cfg.NonceTTL = 5 * time.Minute
+	}
+	if cfg.MaxNonces <= 0 {
+		cfg.MaxNonces = 10000
+	}
+	if cfg.Principal == nil {
+		cfg.Principal = defaultPrincipal
+	}
+
+	nonces := newDigestNonces(cfg.NonceTTL, cfg.MaxNonces)
+	opaque := randomHex(16)
+
+	challenge := func(c *mig.Context, stale bool) error {
+		nonce := nonces.issue()
+		for _, alg := range cfg.Algorithms {
+			v := "Digest realm=" + quotedRealm +
+				`, qop="auth", algorithm=` + alg +
+				`, nonce="` + nonce + `", opaque="` + opaque + `"`
+			if stale {
+				v += ", stale=true"
+			}
+			c.Response.Header().Add("WWW-Authenticate", v)
+		}
+		return mig.NewHTTPError(http.StatusUnauthorized)
+	}
+
+	return func(next mig.Handler) mig.Handler {
+		return func(c *mig.Context) error {
+			scheme, rest, _ := strings.Cut(c.Request.Header.Get("Authorization"), " ")
+			if !strings.EqualFold(scheme, "Digest") {
+				return challenge(c, false)
+			}
+			p := parseAuthParams(rest)
+
+			alg := p["algorithm"]
+			if alg == "" {
+				alg = DigestMD5
+			}
+			h := digestHash(alg)
+			if h == nil || !containsFold(cfg.Algorithms, alg) ||
+				p["realm"] != realm || p["qop"] != "auth" || p["opaque"] != opaque ||
+				p["uri"] != c.Request.RequestURI || p["cnonce"] == "" {
+				return challenge(c, false)
+			}
+			nc, err := strconv.ParseUint(p["nc"], 16, 64)
+			if err != nil {
+				return challenge(c, false)
+			}
+
+			user := p["username"]
+			password, ok := cfg.Password(user)
+			if !ok {
+				return challenge(c, false)
+			}
+
+			ha1 := digestHex(h, user+":"+realm+":"+password)
+			ha2 := digestHex(h, c.Request.Method+":"+p["uri"])
+			expected := digestHex(h, ha1+":"+p["nonce"]+":"+p["nc"]+":"+p["cnonce"]+":auth:"+ha2)
+			if subtle.ConstantTimeCompare([]byte(expected), []byte(strings.ToLower(p["response"]))) != 1 {
+				return challenge(c, false)
+			}
+
+			// Credentials are valid; an unknown or expired nonce only means the
+			// client must retry with a fresh one.
+			if !nonces.use(p["nonce"], nc) {
+				return challenge(c, true)
+			}
+
+			c.SetPrincipal(cfg.Principal(user))
+			return next(c)
+		}
+	}
+}
+
+type digestNonce struct {
+	issued int64
+	nc     uint64
+}
+
+// digestNonces issues nonces made of the issue time, a random salt and their
+// HMAC, and remembers the highest nonce count seen for up to max nonces in use.
+// Nonces issued at or before floor that are not remembered were forgotten
+// to make room and are rejected.
+type digestNonces struct {
+	key []byte
+	ttl time.Duration
+	max int
+
+	mu    sync.Mutex
+	used  map[string]*digestNonce
+	floor int64
+}
+
+func newDigestNonces(ttl time.Duration, max int) *digestNonces {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		panic(err)
+	}
+	return &digestNonces{key: key, ttl: ttl, max: max, used: make(map[string]*digestNonce)}
+}
+
+func (n *digestNonces) issue() string {
+	return n.sign(time.Now().UnixNano(), randomHex(8))
+}
+
+// sign returns the nonce for the issue time and a random salt, which keeps
+// nonces issued in the same clock tick apart.
+func (n *digestNonces) sign(issued int64, salt string) string {
+	prefix := fmt.Sprintf("%016x", uint64(issued)) + salt
+	mac := hmac.New(sha256.New, n.key)
+	io.WriteString(mac, prefix)
+	return prefix + hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// use reports whether nonce was issued by n, has not expired, and nc is
+// higher than any count used with it before.
+func (n *digestNonces) use(nonce string, nc uint64) bool {
+	if len(nonce) != 64 {
+		return false
+	}
+	ts, err := strconv.ParseUint(nonce[:16], 16, 64)
+	if err != nil || !hmac.Equal([]byte(n.sign(int64(ts), nonce[16:32])), []byte(nonce)) {
+		return false
+	}
+	issued, now := int64(ts), time.Now().UnixNano()
+	if now-issued > int64(n.ttl) {
+		return false
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	s, ok := n.used[nonce]
+	if !ok {
+		if issued <= n.floor {
+			return false
+		}
+		if len(n.used) >= n.max {
+			n.evict(now)
+		}
+		s = &digestNonce{issued: issued}
+		n.used[nonce] = s
+	}
+	if nc <= s.nc {
+		return false
+	}
+	s.nc = nc
+	return true
+}
+
+// evict removes expired nonces, or the oldest one if none has expired.
+func (n *digestNonces) evict(now int64) {
+	oldest := ""
+	for k, v := range n.used {
+		if now-v.issued > int64(n.ttl) {
+			delete(n.used, k)
+		} else if oldest == "" || v.issued < n.used[oldest].issued {
+			oldest = k
+		}
+	}
+	if len(n.used) >= n.max && oldest != "" {
+		n.floor = max(n.floor, n.used[oldest].issued)
+		delete(n.used, oldest)
+	}
+}
+
+func digestHash(alg string) func() hash.Hash {
+	switch strings.ToUpper(alg) {
+	case DigestSHA256:
+		return sha256.New
+	case DigestMD5:
+		return md5.New
+	}
+	return nil
+}
+
+func digestHex(h func() hash.Hash, s string) string {
+	d := h()
+	io.WriteString(d, s)
+	return hex.EncodeToString(d.Sum(nil))
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// parseAuthParams parses a comma-separated list of auth-params
+// (key=token or key="quoted string") from an Authorization header.
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if s == "" {
+			return params
+		}
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			return params
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = strings.TrimLeft(s[eq+1:], " \t")
+
+		var val string
+		if strings.HasPrefix(s, `"`) {
+			var b strings.Builder
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				b.WriteByte(s[i])
+			}
+			val = b.String()
+			s = s[min(i+1, len(s)):]
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+			val = strings.TrimSpace(s[:end])
+			s = s[end:]
+		}
+		params[key] = val
+	}
+}
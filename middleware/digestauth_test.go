@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/levmv/mig"
+	"github.com/levmv/mig/authz"
+)
+
+// digestAuthorization builds a client Authorization header answering challenge.
+func digestAuthorization(t *testing.T, challenge, user, password, method, uri string, nc int) string {
+	t.Helper()
+	p := parseAuthParams(strings.TrimPrefix(challenge, "Digest "))
+	var h func() hash.Hash = md5.New
+	if p["algorithm"] == DigestSHA256 {
+		h = sha256.New
+	}
+	ncs := fmt.Sprintf("%08x", nc)
+	cnonce := "0a4f113b"
+	ha1 := digestHex(h, user+":"+p["realm"]+":"+password)
+	ha2 := digestHex(h, method+":"+uri)
+	resp := digestHex(h, ha1+":"+p["nonce"]+":"+ncs+":"+cnonce+":auth:"+ha2)
+	return fmt.Sprintf(`Digest username=%q, realm=%q, nonce=%q, uri=%q, algorithm=%s, qop=auth, nc=%s, cnonce=%q, response=%q, opaque=%q`,
+		user, p["realm"], p["nonce"], uri, p["algorithm"], ncs, cnonce, resp, p["opaque"])
+}
+
+func TestDigestAuth(t *testing.T) {
+	m := mig.New(context.Background())
+
+	restoreLogger := setupSilentLogger(m)
+	defer restoreLogger()
+
+	m.Use(DigestAuthWithConfig(DigestAuthConfig{
+		Password: func(user string) (string, bool) {
+			return "secret", user == "mig"
+		},
+		Realm: "Test Realm",
+	}))
+	m.GET("/protected", func(c *mig.Context) error {
+		return c.Raw([]byte("Hello, " + authz.PrincipalFrom(c).ID()))
+	})
+
+	do := func(auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		m.Mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("")
+	assertEqual(t, http.StatusUnauthorized, rec.Code, "Unauthenticated status")
+	challenges := rec.Header().Values("WWW-Authenticate")
+	assertEqual(t, 2, len(challenges), "Challenge count")
+	if !strings.Contains(challenges[0], "algorithm=SHA-256") || !strings.Contains(challenges[1], "algorithm=MD5") {
+		t.Fatalf("unexpected challenges: %q", challenges)
+	}
+	if !strings.Contains(challenges[0], `realm="Test Realm"`) {
+		t.Fatalf("challenge is missing realm: %q", challenges[0])
+	}
+
+	// Both challenges share one nonce, so nonce counts must keep increasing across them.
+	for i, challenge := range challenges {
+		nc := 2*i + 1
+		auth := digestAuthorization(t, challenge, "mig", "secret", http.MethodGet, "/protected", nc)
+		rec = do(auth)
+		assertEqual(t, http.StatusOK, rec.Code, "Valid credentials status")
+		assertEqual(t, "Hello, mig", rec.Body.String(), "Response body")
+
+		rec = do(auth)
+		assertEqual(t, http.StatusUnauthorized, rec.Code, "Replayed nonce count status")
+
+		rec = do(digestAuthorization(t, challenge, "mig", "secret", http.MethodGet, "/protected", nc+1))
+		assertEqual(t, http.StatusOK, rec.Code, "Incremented nonce count status")
+	}
+
+	rec = do(digestAuthorization(t, challenges[0], "mig", "wrong", http.MethodGet, "/protected", 5))
+	assertEqual(t, http.StatusUnauthorized, rec.Code, "Wrong password status")
+
+	rec = do(digestAuthorization(t, challenges[0], "mig", "secret", http.MethodGet, "/other", 6))
+	assertEqual(t, http.StatusUnauthorized, rec.Code, "Mismatched uri status")
+}
+
+func TestDigestAuth_StaleNonce(t *testing.T) {
+	m := mig.New(context.Background())
+
+	restoreLogger := setupSilentLogger(m)
+	defer restoreLogger()
+
+	m.Use(DigestAuthWithConfig(DigestAuthConfig{
+		Password: func(user string) (string, bool) { return "secret", true },
+		NonceTTL: time.Millisecond,
+	}))
+	m.GET("/", func(c *mig.Context) error { return c.Raw([]byte("OK")) })
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	m.Mux.ServeHTTP(rec, req)
+	challenge := rec.Header().Get("WWW-Authenticate")
+
+	time.Sleep(5 * time.Millisecond)
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", digestAuthorization(t, challenge, "mig", "secret", http.MethodGet, "/", 1))
+	rec = httptest.NewRecorder()
+	m.Mux.ServeHTTP(rec, req)
+
+	assertEqual(t, http.StatusUnauthorized, rec.Code, "Expired nonce status")
+	if !strings.Contains(rec.Header().Get("WWW-Authenticate"), "stale=true") {
+		t.Errorf("expected stale=true in challenge, got %q", rec.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func TestDigestNonces(t *testing.T) {
+	n := newDigestNonces(time.Minute, 2)
+
+	for range 100 {
+		n.issue()
+	}
+	assertEqual(t, 0, len(n.used), "Issuing nonces keeps no state")
+
+	a, b, c := n.issue(), n.issue(), n.issue()
+	forged := a[:len(a)-1] + "0"
+	if strings.HasSuffix(a, "0") {
+		forged = a[:len(a)-1] + "1"
+	}
+	assertEqual(t, false, n.use(forged, 1), "Forged nonce rejected")
+	assertEqual(t, false, n.use(n.sign(time.Now().Add(-time.Hour).UnixNano(), randomHex(8)), 1), "Expired nonce rejected")
+	assertEqual(t, true, n.sign(1, randomHex(8)) != n.sign(1, randomHex(8)), "Nonces issued at the same time differ")
+
+	assertEqual(t, true, n.use(a, 1), "First use")
+	assertEqual(t, true, n.use(b, 1), "Second nonce")
+	assertEqual(t, true, n.use(c, 1), "Third nonce evicts the oldest")
+	assertEqual(t, 2, len(n.used), "Store is capped")
+	assertEqual(t, false, n.use(a, 2), "Forgotten nonce must be renewed")
+	assertEqual(t, false, n.use(c, 1), "Replayed nonce count")
+	assertEqual(t, true, n.use(c, 2), "Incremented nonce count")
+}
@@ -0,0 +1,371 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Htpasswd verifies credentials against an Apache htpasswd file.
+// Its IsAllowed method plugs directly into BasicAuthConfig:
+//
+//	users, err := middleware.LoadHtpasswd("/etc/app/htpasswd")
+//	...
+//	m.Use(middleware.BasicAuthWithConfig(middleware.BasicAuthConfig{
+//		IsAllowed:    users.IsAllowed,
+//		FailureDelay: time.Second,
+//	}))
+//
+// Supported hash formats are {SHA}, $apr1$ (APR1-MD5), $1$ (MD5-crypt),
+// $5$ and $6$ (SHA-256/SHA-512-crypt) and plaintext. Bcrypt entries ($2a$, $2b$, $2y$)
+// are verified with the Bcrypt hook, if set. Legacy DES crypt is not supported.
+//
+// The file is re-read when its modification time or size changes. Changes
+// are checked for at most once per CheckInterval, lazily on IsAllowed calls.
+type Htpasswd struct {
+	// Bcrypt verifies bcrypt hashes, e.g. bcrypt.CompareHashAndPassword from
+	// golang.org/x/crypto/bcrypt. Without it bcrypt entries never match.
+	Bcrypt func(hash, password []byte) error
+	// CheckInterval is how often the file is checked for changes.
+	// Default is 5 seconds. A negative value disables reloading.
+	CheckInterval time.Duration
+
+	path      string
+	mu        sync.RWMutex
+	users     map[string]string
+	modTime   time.Time
+	size      int64
+	lastCheck time.Time
+}
+
+// dummyHash is compared against for unknown users so that a missing user
+// takes about as long to reject as a wrong password.
+const dummyHash = "$apr1$dummysal$4LGrcrf9Xjbv8y4VFDNsA."
+
+// LoadHtpasswd reads an htpasswd file.
+func LoadHtpasswd(path string) (*Htpasswd, error) {
+	h := &Htpasswd{path: path, CheckInterval: 5 * time.Second}
+	if err := h.Reload(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Reload re-reads the file unconditionally. On error the previously loaded
+// entries are kept.
+func (h *Htpasswd) Reload() error {
+	f, err := os.Open(h.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	st, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	users, err := parseHtpasswd(f)
+	if err != nil {
+		return fmt.Errorf("htpasswd %s: %w", h.path, err)
+	}
+
+	h.mu.Lock()
+	h.users = users
+	h.modTime = st.ModTime()
+	h.size = st.Size()
+	h.lastCheck = time.Now()
+	h.mu.Unlock()
+	return nil
+}
+
+func parseHtpasswd(f *os.File) (map[string]string, error) {
+	users := make(map[string]string)
+	sc := bufio.NewScanner(f)
+	line := 0
+	for sc.Scan() {
+		line++
+		s := strings.TrimSpace(sc.Text())
+		if s == "" || s[0] == '#' {
+			continue
+		}
+		user, hash, ok := strings.Cut(s, ":")
+		if !ok || user == "" {
+			return nil, fmt.Errorf("line %d: malformed entry", line)
+		}
+		users[user] = hash
+	}
+	return users, sc.Err()
+}
+
+// IsAllowed reports whether password is valid for user.
+func (h *Htpasswd) IsAllowed(user, password string) bool {
+	h.reloadIfChanged()
+
+	h.mu.RLock()
+	hash, ok := h.users[user]
+	h.mu.RUnlock()
+
+	if !ok {
+		verifyHtpasswdHash(dummyHash, password, nil)
+		return false
+	}
+	return verifyHtpasswdHash(hash, password, h.Bcrypt)
+}
+
+func (h *Htpasswd) reloadIfChanged() {
+	if h.CheckInterval < 0 {
+		return
+	}
+	interval := h.CheckInterval
+	if interval == 0 {
+		interval = 5 * time.Second
+	}
+
+	h.mu.Lock()
+	if time.Since(h.lastCheck) < interval {
+		h.mu.Unlock()
+		return
+	}
+	h.lastCheck = time.Now()
+	modTime, size := h.modTime, h.size
+	h.mu.Unlock()
+
+	st, err := os.Stat(h.path)
+	if err != nil || (st.ModTime().Equal(modTime) && st.Size() == size) {
+		return
+	}
+	_ = h.Reload()
+}
+
+func verifyHtpasswdHash(hash, password string, bcrypt func(hash, password []byte) error) bool {
+	var computed string
+	switch {
+	case strings.HasPrefix(hash, "{SHA}"):
+		sum := sha1.Sum([]byte(password))
+		computed = "{SHA}" + base64.StdEncoding.EncodeToString(sum[:])
+	case strings.HasPrefix(hash, "$apr1$"):
+		computed = md5Crypt(password, hash, "$apr1$")
+	case strings.HasPrefix(hash, "$1$"):
+		computed = md5Crypt(password, hash, "$1$")
+	case strings.HasPrefix(hash, "$5$"):
+		computed = shaCrypt(sha256.New, sha256CryptOrder, password, hash, "$5$")
+	case strings.HasPrefix(hash, "$6$"):
+		computed = shaCrypt(sha512.New, sha512CryptOrder, password, hash, "$6$")
+	case strings.HasPrefix(hash, "$2"):
+		if bcrypt == nil {
+			return false
+		}
+		return bcrypt([]byte(hash), []byte(password)) == nil
+	case strings.HasPrefix(hash, "$"):
+		// Unknown scheme; never fall back to plaintext comparison.
+		return false
+	default:
+		computed = password
+	}
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(hash)) == 1
+}
+
+const cryptAlphabet = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// cryptB64 appends n characters encoding the 24-bit group b2:b1:b0 in the
+// crypt(3) base64 variant (least significant bits first).
+func cryptB64(dst []byte, b2, b1, b0 byte, n int) []byte {
+	w := uint(b2)<<16 | uint(b1)<<8 | uint(b0)
+	for ; n > 0; n-- {
+		dst = append(dst, cryptAlphabet[w&0x3f])
+		w >>= 6
+	}
+	return dst
+}
+
+// cryptSalt extracts the salt from a "$magic$salt$hash" string.
+func cryptSalt(hash, magic string, maxLen int) string {
+	salt := strings.TrimPrefix(hash, magic)
+	if i := strings.IndexByte(salt, '$'); i >= 0 {
+		salt = salt[:i]
+	}
+	if len(salt) > maxLen {
+		salt = salt[:maxLen]
+	}
+	return salt
+}
+
+// md5Crypt implements the MD5-based crypt used by "$1$" and Apache's "$apr1$".
+func md5Crypt(password, hash, magic string) string {
+	pw := []byte(password)
+	salt := []byte(cryptSalt(hash, magic, 8))
+
+	alt := md5.New()
+	alt.Write(pw)
+	alt.Write(salt)
+	alt.Write(pw)
+	altSum := alt.Sum(nil)
+
+	d := md5.New()
+	d.Write(pw)
+	d.Write([]byte(magic))
+	d.Write(salt)
+	for i := len(pw); i > 0; i -= 16 {
+		d.Write(altSum[:min(i, 16)])
+	}
+	for i := len(pw); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			d.Write([]byte{0})
+		} else {
+			d.Write(pw[:1])
+		}
+	}
+	final := d.Sum(nil)
+
+	for i := range 1000 {
+		r := md5.New()
+		if i&1 != 0 {
+			r.Write(pw)
+		} else {
+			r.Write(final)
+		}
+		if i%3 != 0 {
+			r.Write(salt)
+		}
+		if i%7 != 0 {
+			r.Write(pw)
+		}
+		if i&1 != 0 {
+			r.Write(final)
+		} else {
+			r.Write(pw)
+		}
+		final = r.Sum(nil)
+	}
+
+	out := make([]byte, 0, len(magic)+len(salt)+1+22)
+	out = append(out, magic...)
+	out = append(out, salt...)
+	out = append(out, '$')
+	for _, g := range [][3]int{{0, 6, 12}, {1, 7, 13}, {2, 8, 14}, {3, 9, 15}, {4, 10, 5}} {
+		out = cryptB64(out, final[g[0]], final[g[1]], final[g[2]], 4)
+	}
+	out = cryptB64(out, 0, 0, final[11], 2)
+	return string(out)
+}
+
+// Byte orders used to encode the final digest in SHA-crypt output.
+var (
+	sha256CryptOrder = [][3]int{
+		{0, 10, 20}, {21, 1, 11}, {12, 22, 2}, {3, 13, 23}, {24, 4, 14},
+		{15, 25, 5}, {6, 16, 26}, {27, 7, 17}, {18, 28, 8}, {9, 19, 29},
+	}
+	sha512CryptOrder = [][3]int{
+		{0, 21, 42}, {22, 43, 1}, {44, 2, 23}, {3, 24, 45}, {25, 46, 4},
+		{47, 5, 26}, {6, 27, 48}, {28, 49, 7}, {50, 8, 29}, {9, 30, 51},
+		{31, 52, 10}, {53, 11, 32}, {12, 33, 54}, {34, 55, 13}, {56, 14, 35},
+		{15, 36, 57}, {37, 58, 16}, {59, 17, 38}, {18, 39, 60}, {40, 61, 19},
+		{62, 20, 41},
+	}
+)
+
+// shaCrypt implements the SHA-256 ("$5$") and SHA-512 ("$6$") crypt schemes.
+func shaCrypt(newHash func() hash.Hash, order [][3]int, password, stored, magic string) string {
+	const roundsPrefix = "rounds="
+	rounds, customRounds := 5000, false
+	rest := strings.TrimPrefix(stored, magic)
+	if strings.HasPrefix(rest, roundsPrefix) {
+		spec, after, _ := strings.Cut(rest[len(roundsPrefix):], "$")
+		n, err := strconv.Atoi(spec)
+		if err != nil {
+			return ""
+		}
+		rounds, customRounds = min(max(n, 1000), 999999999), true
+		rest = after
+	}
+	pw := []byte(password)
+	salt := []byte(cryptSalt(rest, "", 16))
+
+	b := newHash()
+	b.Write(pw)
+	b.Write(salt)
+	b.Write(pw)
+	bSum := b.Sum(nil)
+	size := len(bSum)
+
+	a := newHash()
+	a.Write(pw)
+	a.Write(salt)
+	for i := len(pw); i > 0; i -= size {
+		a.Write(bSum[:min(i, size)])
+	}
+	for i := len(pw); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			a.Write(bSum)
+		} else {
+			a.Write(pw)
+		}
+	}
+	aSum := a.Sum(nil)
+
+	dp := newHash()
+	for range len(pw) {
+		dp.Write(pw)
+	}
+	pSeq := repeatToLen(dp.Sum(nil), len(pw))
+
+	ds := newHash()
+	for range 16 + int(aSum[0]) {
+		ds.Write(salt)
+	}
+	sSeq := repeatToLen(ds.Sum(nil), len(salt))
+
+	c := aSum
+	for i := range rounds {
+		r := newHash()
+		if i&1 != 0 {
+			r.Write(pSeq)
+		} else {
+			r.Write(c)
+		}
+		if i%3 != 0 {
+			r.Write(sSeq)
+		}
+		if i%7 != 0 {
+			r.Write(pSeq)
+		}
+		if i&1 != 0 {
+			r.Write(c)
+		} else {
+			r.Write(pSeq)
+		}
+		c = r.Sum(nil)
+	}
+
+	out := []byte(magic)
+	if customRounds {
+		out = append(out, roundsPrefix+strconv.Itoa(rounds)+"$"...)
+	}
+	out = append(out, salt...)
+	out = append(out, '$')
+	for _, g := range order {
+		out = cryptB64(out, c[g[0]], c[g[1]], c[g[2]], 4)
+	}
+	if size == sha256.Size {
+		out = cryptB64(out, 0, c[31], c[30], 3)
+	} else {
+		out = cryptB64(out, 0, 0, c[63], 2)
+	}
+	return string(out)
+}
+
+func repeatToLen(b []byte, n int) []byte {
+	return bytes.Repeat(b, n/len(b)+1)[:n]
+}
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/levmv/mig"
+)
+
+const testHtpasswd = `# comment
+sha:{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ=
+apr:$apr1$saltsalt$LrttParrLPdxvgutaSXWJ0
+md5:$1$saltsalt$9xy1btjgzLYfb7hivXtC//
+sha256:$5$abcdefgh$Q3HL3fyO1X2phL4HcbLKrQIvNmLlASe4ayT3RIc4ZbD
+sha512:$6$rounds=10000$saltstringsaltst$OW1/O6BYHV6BcXZu8QVeXbDWra3Oeqh0sbHbbMCVNSnCM/UrjmM0Dp8vOuZeHBy/YTBmSK6H9qs/y3RnOaw5v.
+plain:secret
+bcrypt:$2y$05$fakebcrypthash
+`
+
+func TestHtpasswd_IsAllowed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "htpasswd")
+	assertNoError(t, os.WriteFile(path, []byte(testHtpasswd), 0o600), "Failed to write htpasswd")
+
+	h, err := LoadHtpasswd(path)
+	assertNoError(t, err, "LoadHtpasswd failed")
+	h.Bcrypt = func(hash, password []byte) error {
+		if string(hash) == "$2y$05$fakebcrypthash" && string(password) == "secret" {
+			return nil
+		}
+		return errors.New("mismatch")
+	}
+
+	testCases := []struct {
+		user, password string
+		expect         bool
+	}{
+		{"sha", "secret", true},
+		{"sha", "wrong", false},
+		{"apr", "secret", true},
+		{"apr", "wrong", false},
+		{"md5", "secret", true},
+		{"sha256", "pw5", true},
+		{"sha256", "pw6", false},
+		{"sha512", "Hello world!", true},
+		{"sha512", "Hello world", false},
+		{"plain", "secret", true},
+		{"plain", "secret2", false},
+		{"bcrypt", "secret", true},
+		{"bcrypt", "wrong", false},
+		{"nobody", "secret", false},
+	}
+	for _, tc := range testCases {
+		assertEqual(t, tc.expect, h.IsAllowed(tc.user, tc.password), tc.user+":"+tc.password)
+	}
+}
+
+func TestHtpasswd_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "htpasswd")
+	assertNoError(t, os.WriteFile(path, []byte("alice:secret\n"), 0o600), "Failed to write htpasswd")
+
+	h, err := LoadHtpasswd(path)
+	assertNoError(t, err, "LoadHtpasswd failed")
+	h.CheckInterval = time.Nanosecond
+
+	assertEqual(t, true, h.IsAllowed("alice", "secret"), "alice before reload")
+
+	assertNoError(t, os.WriteFile(path, []byte("bob:secret\n"), 0o600), "Failed to rewrite htpasswd")
+	future := time.Now().Add(time.Minute)
+	assertNoError(t, os.Chtimes(path, future, future), "Failed to touch htpasswd")
+
+	assertEqual(t, false, h.IsAllowed("alice", "secret"), "alice after reload")
+	assertEqual(t, true, h.IsAllowed("bob", "secret"), "bob after reload")
+}
+
+func TestBasicAuth_FailureDelay(t *testing.T) {
+	m := mig.New(context.Background())
+
+	restoreLogger := setupSilentLogger(m)
+	defer restoreLogger()
+
+	m.Use(BasicAuthWithConfig(BasicAuthConfig{
+		IsAllowed:    func(user, password string) bool { return false },
+		FailureDelay: 50 * time.Millisecond,
+	}))
+	m.GET("/", func(c *mig.Context) error { return c.Raw([]byte("OK")) })
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.SetBasicAuth("mig", "wrong")
+	rec := httptest.NewRecorder()
+
+	start := time.Now()
+	m.Mux.ServeHTTP(rec, req)
+
+	assertEqual(t, http.StatusUnauthorized, rec.Code, "Status code mismatch")
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("expected failed attempt to be delayed, took %v", elapsed)
+	}
+}
@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/levmv/mig"
+)
+
+// ConcurrencyLimitConfig configures ConcurrencyLimitWithConfig.
+type ConcurrencyLimitConfig struct {
+	// MaxInFlight is the maximum number of requests handled at once.
+	// With Adaptive it is the upper bound of the limit. Required.
+	MaxInFlight int
+	// QueueSize is how many requests may wait for a free slot. When the
+	// queue is full, requests are shed immediately. Default is 0 (no queue).
+	QueueSize int
+	// QueueTimeout is how long a request may wait in the queue before it is
+	// shed. Default is 1 second.
+	QueueTimeout time.Duration
+	// RetryAfter is sent in the Retry-After header of shed requests.
+	// Default is 1 second.
+	RetryAfter time.Duration
+	// Skip exempts matching requests, e.g. health checks, from the limit.
+	Skip func(c *mig.Context) bool
+
+	// Adaptive adjusts the limit between MinInFlight and MaxInFlight from
+	// observed latency (AIMD): requests finishing within LatencyTarget raise
+	// the limit by one per limit-many requests, slower requests and server
+	// errors lower it by DecreaseFactor. Requests started before the last
+	// decrease don't lower it again, so a burst of slow requests counts as
+	// one congestion signal.
+	Adaptive       bool
+	MinInFlight    int           // default 1
+	LatencyTarget  time.Duration // required with Adaptive
+	DecreaseFactor float64       // default 0.9
+}
+
+// ConcurrencyLimit returns a middleware that handles at most max requests at
+// once and answers the rest with 503 Service Unavailable.
+func ConcurrencyLimit(max int) mig.MiddlewareFunc {
+	return ConcurrencyLimitWithConfig(ConcurrencyLimitConfig{MaxInFlight: max})
+}
+
+// ConcurrencyLimitWithConfig returns a concurrency limiting middleware with
+// an optional wait queue and adaptive limit. Shed requests get
+// 503 Service Unavailable with a Retry-After header.
+func ConcurrencyLimitWithConfig(cfg ConcurrencyLimitConfig) mig.MiddlewareFunc {
+	if cfg.MaxInFlight <= 0 {
+		panic("ConcurrencyLimit middleware requires MaxInFlight")
+	}
+	if cfg.Adaptive && cfg.LatencyTarget <= 0 {
+		panic("adaptive ConcurrencyLimit middleware requires LatencyTarget")
+	}
+	if cfg.QueueTimeout <= 0 {
+		cfg.QueueTimeout = time.Second
+	}
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = time.Second
+	}
+	if cfg.MinInFlight <= 0 {
+		cfg.MinInFlight = 1
+	}
+	if cfg.DecreaseFactor <= 0 || cfg.DecreaseFactor >= 1 {
+		cfg.DecreaseFactor = 0.9
+	}
+	retryAfter := strconv.Itoa(int((cfg.RetryAfter + time.Second - 1) / time.Second))
+	l := &concurrencyLimiter{cfg: cfg, limit: float64(cfg.MaxInFlight)}
+
+	return func(next mig.Handler) mig.Handler {
+		return func(c *mig.Context) (err error) {
+			if cfg.Skip != nil && cfg.Skip(c) {
+				return next(c)
+			}
+			epoch, ok := l.acquire(c.Request.Context())
+			if !ok {
+				c.Response.Header().Set("Retry-After", retryAfter)
+				return mig.NewHTTPError(http.StatusServiceUnavailable)
+			}
+
+			start := time.Now()
+			panicked := true
+			defer func() {
+				failed := panicked || c.ResponseStatus(err) >= 500
+				l.release(epoch, time.Since(start), failed)
+			}()
+			err = next(c)
+			panicked = false
+			return err
+		}
+	}
+}
+
+type concurrencyLimiter struct {
+	cfg ConcurrencyLimitConfig
+
+	mu       sync.Mutex
+	limit    float64
+	inFlight int
+	waiters  []chan struct{}
+	// epoch counts decreases of the adaptive limit.
+	epoch uint64
+}
+
+// acquire takes a slot, waiting in the queue if there is room in it. It
+// returns the current epoch, to be passed to release.
+func (l *concurrencyLimiter) acquire(ctx context.Context) (uint64, bool) {
+	l.mu.Lock()
+	if l.inFlight < int(l.limit) {
+		l.inFlight++
+		epoch := l.epoch
+		l.mu.Unlock()
+		return epoch, true
+	}
+	if len(l.waiters) >= l.cfg.QueueSize {
+		l.mu.Unlock()
+		return 0, false
+	}
+	ready := make(chan struct{})
+	l.waiters = append(l.waiters, ready)
+	l.mu.Unlock()
+
+	t := time.NewTimer(l.cfg.QueueTimeout)
+	defer t.Stop()
+	select {
+	case <-ready:
+	case <-t.C:
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if i := slices.Index(l.waiters, ready); i >= 0 {
+		l.waiters = slices.Delete(l.waiters, i, i+1)
+		return 0, false
+	}
+	return l.epoch, true // handed a slot, possibly just as the wait ended
+}
+
+// release frees a slot, adjusts the adaptive limit and wakes queued requests.
+// Only requests acquired in the current epoch can decrease the limit.
+func (l *concurrencyLimiter) release(epoch uint64, latency time.Duration, failed bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight--
+	if l.cfg.Adaptive {
+		if failed || latency > l.cfg.LatencyTarget {
+			if epoch == l.epoch {
+				l.limit = max(float64(l.cfg.MinInFlight), l.limit*l.cfg.DecreaseFactor)
+				l.epoch++
+			}
+		} else {
+			l.limit = min(float64(l.cfg.MaxInFlight), l.limit+1/l.limit)
+		}
+	}
+	for l.inFlight < int(l.limit) && len(l.waiters) > 0 {
+		close(l.waiters[0])
+		l.waiters = l.waiters[1:]
+		l.inFlight++
+	}
+}
@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/levmv/mig"
+)
+
+func TestConcurrencyLimit(t *testing.T) {
+	m := mig.New(context.Background())
+	restoreLogger := setupSilentLogger(m)
+	defer restoreLogger()
+
+	started := make(chan struct{}, 2)
+	unblock := make(chan struct{})
+	m.Use(ConcurrencyLimitWithConfig(ConcurrencyLimitConfig{
+		MaxInFlight:  1,
+		QueueSize:    1,
+		QueueTimeout: time.Second,
+		RetryAfter:   3 * time.Second,
+	}))
+	m.GET("/", func(c *mig.Context) error {
+		started <- struct{}{}
+		<-unblock
+		return c.Raw([]byte("OK"))
+	})
+
+	serve := func() chan *httptest.ResponseRecorder {
+		done := make(chan *httptest.ResponseRecorder, 1)
+		go func() {
+			rec := httptest.NewRecorder()
+			m.Mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			done <- rec
+		}()
+		return done
+	}
+
+	first := serve()
+	<-started
+	queued := serve()
+	time.Sleep(20 * time.Millisecond) // let the second request enter the queue
+
+	rec := <-serve()
+	assertEqual(t, http.StatusServiceUnavailable, rec.Code, "Shed when the queue is full")
+	assertEqual(t, "3", rec.Header().Get("Retry-After"), "Retry-After")
+
+	unblock <- struct{}{}
+	assertEqual(t, http.StatusOK, (<-first).Code, "First request")
+	<-started
+	unblock <- struct{}{}
+	assertEqual(t, http.StatusOK, (<-queued).Code, "Queued request served after the first")
+}
+
+func TestConcurrencyLimit_QueueTimeout(t *testing.T) {
+	l := &concurrencyLimiter{
+		cfg:   ConcurrencyLimitConfig{MaxInFlight: 1, QueueSize: 1, QueueTimeout: 10 * time.Millisecond},
+		limit: 1,
+	}
+	_, ok := l.acquire(context.Background())
+	assertEqual(t, true, ok, "First acquire")
+	_, ok = l.acquire(context.Background())
+	assertEqual(t, false, ok, "Queued acquire times out")
+	assertEqual(t, 0, len(l.waiters), "Timed out waiter removed")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, ok = l.acquire(ctx)
+	assertEqual(t, false, ok, "Cancelled request leaves the queue")
+
+	l.release(0, 0, false)
+	assertEqual(t, 0, l.inFlight, "Slot released")
+}
+
+func TestConcurrencyLimit_Adaptive(t *testing.T) {
+	l := &concurrencyLimiter{
+		cfg: ConcurrencyLimitConfig{
+			MaxInFlight: 10, MinInFlight: 2, Adaptive: true,
+			LatencyTarget: 100 * time.Millisecond, DecreaseFactor: 0.5,
+		},
+		limit: 10,
+	}
+	for range 5 {
+		epoch, _ := l.acquire(context.Background())
+		l.release(epoch, time.Second, false)
+	}
+	assertEqual(t, 2.0, l.limit, "Slow requests shrink the limit down to MinInFlight")
+
+	epoch, _ := l.acquire(context.Background())
+	l.release(epoch, time.Millisecond, false)
+	assertEqual(t, 2.5, l.limit, "Fast requests grow the limit additively")
+
+	epoch, _ = l.acquire(context.Background())
+	l.release(epoch, time.Millisecond, true)
+	assertEqual(t, 2.0, l.limit, "Server errors shrink the limit")
+}
+
+func TestConcurrencyLimit_AdaptiveBurst(t *testing.T) {
+	l := &concurrencyLimiter{
+		cfg: ConcurrencyLimitConfig{
+			MaxInFlight: 100, MinInFlight: 1, Adaptive: true,
+			LatencyTarget: 100 * time.Millisecond, DecreaseFactor: 0.5,
+		},
+		limit: 100,
+	}
+	epochs := make([]uint64, 100)
+	for i := range epochs {
+		epochs[i], _ = l.acquire(context.Background())
+	}
+	for _, epoch := range epochs {
+		l.release(epoch, time.Second, false)
+	}
+	assertEqual(t, 50.0, l.limit, "A burst of slow requests decreases the limit once")
+
+	epoch, _ := l.acquire(context.Background())
+	l.release(epoch, time.Second, false)
+	assertEqual(t, 25.0, l.limit, "A request started after the decrease decreases it again")
+}
@@ -1,33 +1,244 @@
 package middleware
 
 import (
+	"context"
+	"io"
 	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/levmv/mig"
+	"github.com/levmv/mig/authz"
 )
 
+// LogFormat selects the output format of RequestLoggerWithConfig.
+type LogFormat int
+
+const (
+	// LogFormatSlog logs through c.Logger, so request IDs and other context
+	// attached to the logger are included. This is the default.
+	LogFormatSlog LogFormat = iota
+	// LogFormatCommon writes Apache Common Log Format lines to Output.
+	LogFormatCommon
+	// LogFormatCombined writes Apache Combined Log Format lines to Output.
+	LogFormatCombined
+	// LogFormatJSON writes one JSON object per request to Output.
+	LogFormatJSON
+)
+
+// LogField selects optional fields logged in the slog and JSON formats.
+type LogField uint
+
+const (
+	LogClientIP LogField = 1 << iota
+	LogUserAgent
+	LogReferer
+	LogRoute
+	LogRequestSize
+	LogRequestID
+	LogProtocol
+)
+
+// RequestLoggerConfig configures RequestLoggerWithConfig.
+// The zero value logs every request at Info level through c.Logger.
+type RequestLoggerConfig struct {
+	Format LogFormat
+	// Fields adds optional fields to the slog and JSON formats. Method, path,
+	// status, response bytes and duration are always logged. In LogFormatSlog the
+	// request ID is usually already attached to c.Logger by RequestID.
+	Fields LogField
+	// Output receives Common, Combined and JSON lines. Default is os.Stdout.
+	Output io.Writer
+	// Skip prevents logging of matching requests, e.g. health checks.
+	Skip func(c *mig.Context) bool
+	// SuccessSampleRate logs only this fraction (0..1) of requests answered
+	// with a status below 400 that were not slow. Zero logs all of them.
+	SuccessSampleRate float64
+
+	// Level is used for ordinary requests. The levels below are used instead
+	// when they are higher and the request matches. Defaults are all Info.
+	Level            slog.Level
+	ClientErrorLevel slog.Level // 4xx responses
+	ServerErrorLevel slog.Level // 5xx responses and panics
+	SlowLevel        slog.Level // requests taking longer than SlowThreshold
+	SlowThreshold    time.Duration
+}
+
 // RequestLogger returns a middleware that logs one line per request with timing info.
 // It ensures a log entry is written even if the handler panics.
 //
 // NOTE: For request IDs to appear in logs, you must register the RequestID()
 // middleware before RequestLogger, e.g.: m.Use(RequestID(), RequestLogger()).
 func RequestLogger() mig.MiddlewareFunc {
+	return RequestLoggerWithConfig(RequestLoggerConfig{Fields: LogRoute})
+}
+
+// RequestLoggerWithConfig returns a request logging middleware with configurable
+// format, fields, filtering, sampling and level escalation.
+func RequestLoggerWithConfig(cfg RequestLoggerConfig) mig.MiddlewareFunc {
+	if cfg.Output == nil {
+		cfg.Output = os.Stdout
+	}
+	var jsonLogger *slog.Logger
+	if cfg.Format == LogFormatJSON {
+		jsonLogger = slog.New(slog.NewJSONHandler(cfg.Output, &slog.HandlerOptions{Level: slog.LevelDebug - 4}))
+	}
+	var mu sync.Mutex // serializes Common/Combined lines
+
 	return func(next mig.Handler) mig.Handler {
-		return func(c *mig.Context) error {
+		return func(c *mig.Context) (err error) {
 			start := time.Now()
 
+			panicked := true
 			defer func() {
-				c.Logger.Info("request",
-					slog.String("method", c.Request.Method),
-					slog.String("path", c.Request.URL.Path),
-					slog.Int("status", c.Response.Status()),
-					slog.Int("bytes", c.Response.Written()),
-					slog.Duration("t", time.Since(start)),
-				)
+				if cfg.Skip != nil && cfg.Skip(c) {
+					return
+				}
+				status := http.StatusInternalServerError
+				if !panicked {
+					status = c.ResponseStatus(err)
+				}
+				elapsed := time.Since(start)
+				slow := cfg.SlowThreshold > 0 && elapsed > cfg.SlowThreshold
+
+				level := cfg.Level
+				switch {
+				case status >= 500:
+					level = max(level, cfg.ServerErrorLevel)
+				case status >= 400:
+					level = max(level, cfg.ClientErrorLevel)
+				case !slow && cfg.SuccessSampleRate > 0 && rand.Float64() >= cfg.SuccessSampleRate:
+					return
+				}
+				if slow {
+					level = max(level, cfg.SlowLevel)
+				}
+
+				switch cfg.Format {
+				case LogFormatCommon, LogFormatCombined:
+					line := accessLogLine(c, start, status, cfg.Format == LogFormatCombined)
+					mu.Lock()
+					io.WriteString(cfg.Output, line)
+					mu.Unlock()
+				case LogFormatJSON:
+					jsonLogger.LogAttrs(context.Background(), level, "request", requestLogAttrs(c, &cfg, status, elapsed)...)
+				default:
+					c.Logger.LogAttrs(context.Background(), level, "request", requestLogAttrs(c, &cfg, status, elapsed)...)
+				}
 			}()
 
-			return next(c)
+			err = next(c)
+			panicked = false
+			return err
 		}
 	}
 }
+
+func requestLogAttrs(c *mig.Context, cfg *RequestLoggerConfig, status int, elapsed time.Duration) []slog.Attr {
+	attrs := make([]slog.Attr, 0, 12)
+	attrs = append(attrs,
+		slog.String("method", c.Request.Method),
+		slog.String("path", c.Request.URL.Path),
+	)
+	if cfg.Fields&LogRoute != 0 {
+		var route string
+		if r := c.Route(); r != nil {
+			route = r.Pattern
+		}
+		attrs = append(attrs, slog.String("route", route))
+	}
+	attrs = append(attrs,
+		slog.Int("status", status),
+		slog.Int("bytes", c.Response.Written()),
+		slog.Duration("t", elapsed),
+	)
+	if cfg.Fields&LogClientIP != 0 {
+		attrs = append(attrs, slog.String("ip", c.RealIP()))
+	}
+	if cfg.Fields&LogUserAgent != 0 {
+		attrs = append(attrs, slog.String("user_agent", c.Request.UserAgent()))
+	}
+	if cfg.Fields&LogReferer != 0 {
+		attrs = append(attrs, slog.String("referer", c.Request.Referer()))
+	}
+	if cfg.Fields&LogRequestSize != 0 {
+		attrs = append(attrs, slog.Int64("request_bytes", max(c.Request.ContentLength, 0)))
+	}
+	if cfg.Fields&LogRequestID != 0 {
+		attrs = append(attrs, slog.String("id", c.RequestID()))
+	}
+	if cfg.Fields&LogProtocol != 0 {
+		attrs = append(attrs, slog.String("proto", c.Protocol()))
+	}
+	return attrs
+}
+
+// accessLogLine formats a request in Apache Common or Combined Log Format.
+func accessLogLine(c *mig.Context, start time.Time, status int, combined bool) string {
+	user := "-"
+	if u, _, ok := c.Request.BasicAuth(); ok && u != "" {
+		user = u
+	} else if p := authz.PrincipalFrom(c); p != nil && p.ID() != "" {
+		user = p.ID()
+	} else if p, ok := c.Principal().(string); ok && p != "" {
+		user = p
+	}
+	size := "-"
+	if n := c.Response.Written(); n > 0 {
+		size = strconv.Itoa(n)
+	}
+
+	var b strings.Builder
+	b.WriteString(clfField(c.RealIP()))
+	b.WriteString(" - ")
+	b.WriteString(clfField(user))
+	b.WriteString(" [")
+	b.WriteString(start.Format("02/Jan/2006:15:04:05 -0700"))
+	b.WriteString(`] "`)
+	b.WriteString(clfQuoted(c.Request.Method + " " + c.Request.RequestURI + " " + c.Request.Proto))
+	b.WriteString(`" `)
+	b.WriteString(strconv.Itoa(status))
+	b.WriteByte(' ')
+	b.WriteString(size)
+	if combined {
+		b.WriteString(` "`)
+		b.WriteString(clfQuoted(orDash(c.Request.Referer())))
+		b.WriteString(`" "`)
+		b.WriteString(clfQuoted(orDash(c.Request.UserAgent())))
+		b.WriteByte('"')
+	}
+	b.WriteByte('\n')
+	return b.String()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// clfField makes s safe for an unquoted access log field.
+func clfField(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+var clfQuoter = strings.NewReplacer(`"`, `\"`, `\`, `\\`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+// clfQuoted escapes s for a double-quoted access log field.
+func clfQuoted(s string) string {
+	return clfQuoter.Replace(s)
+}
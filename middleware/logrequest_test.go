@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/levmv/mig"
+)
+
+func TestRequestLogger(t *testing.T) {
+	var logs bytes.Buffer
+	m := mig.New(context.Background())
+	m.Logger = slog.New(slog.NewTextHandler(&logs, nil))
+
+	m.Use(RequestID(), RequestLogger())
+	m.GET("/users/{id}", func(c *mig.Context) error { return mig.ErrNotFound })
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set(mig.RequestIDHeader, "abc")
+	m.Mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	line := strings.SplitN(logs.String(), "\n", 2)[0]
+	for _, want := range []string{"msg=request", "id=abc", "method=GET", "path=/users/1", "route=/users/{id}", "status=404"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("log line %q is missing %q", line, want)
+		}
+	}
+}
+
+func TestRequestLoggerWithConfig_Formats(t *testing.T) {
+	testCases := []struct {
+		name   string
+		format LogFormat
+		expect *regexp.Regexp
+	}{
+		{
+			name:   "Common",
+			format: LogFormatCommon,
+			expect: regexp.MustCompile(`^192\.0\.2\.1 - mig \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /page\?x=1 HTTP/1\.1" 200 5\n$`),
+		},
+		{
+			name:   "Combined",
+			format: LogFormatCombined,
+			expect: regexp.MustCompile(`^192\.0\.2\.1 - mig \[[^\]]+\] "GET /page\?x=1 HTTP/1\.1" 200 5 "http://example\.com/" "test \\"agent\\""\n$`),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			m := mig.New(context.Background())
+			m.Use(RequestLoggerWithConfig(RequestLoggerConfig{Format: tc.format, Output: &out}))
+			m.GET("/page", func(c *mig.Context) error { return c.Raw([]byte("hello")) })
+
+			req := httptest.NewRequest(http.MethodGet, "/page?x=1", nil)
+			req.SetBasicAuth("mig", "secret")
+			req.Header.Set("Referer", "http://example.com/")
+			req.Header.Set("User-Agent", `test "agent"`)
+			m.Mux.ServeHTTP(httptest.NewRecorder(), req)
+
+			if !tc.expect.MatchString(out.String()) {
+				t.Errorf("unexpected access log line: %q", out.String())
+			}
+		})
+	}
+}
+
+func TestRequestLoggerWithConfig_JSON(t *testing.T) {
+	var out bytes.Buffer
+	m := mig.New(context.Background())
+	restoreLogger := setupSilentLogger(m)
+	defer restoreLogger()
+
+	m.Use(RequestLoggerWithConfig(RequestLoggerConfig{
+		Format:           LogFormatJSON,
+		Output:           &out,
+		Fields:           LogClientIP | LogUserAgent | LogRoute | LogRequestID,
+		Skip:             func(c *mig.Context) bool { return c.Request.URL.Path == "/healthz" },
+		ServerErrorLevel: slog.LevelError,
+	}))
+	m.GET("/healthz", func(c *mig.Context) error { return c.Raw([]byte("ok")) })
+	m.GET("/fail", func(c *mig.Context) error { panic("boom") })
+
+	m.Mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	m.Mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assertEqual(t, 1, len(lines), "Health check should be skipped")
+
+	var entry map[string]any
+	assertNoError(t, json.Unmarshal([]byte(lines[0]), &entry), "Log line is not JSON")
+	assertEqual(t, "ERROR", entry["level"], "Level should be escalated for 5xx")
+	assertEqual(t, float64(500), entry["status"], "Status of a panicking handler")
+	assertEqual(t, "/fail", entry["route"], "Route field")
+	assertEqual(t, "192.0.2.1", entry["ip"], "Client IP field")
+}
+
+func TestRequestLoggerWithConfig_SamplingAndSlow(t *testing.T) {
+	var out bytes.Buffer
+	m := mig.New(context.Background())
+	m.Use(RequestLoggerWithConfig(RequestLoggerConfig{
+		Format:            LogFormatJSON,
+		Output:            &out,
+		SuccessSampleRate: 1e-12,
+		SlowThreshold:     10 * time.Millisecond,
+		SlowLevel:         slog.LevelWarn,
+	}))
+	m.GET("/fast", func(c *mig.Context) error { return c.Raw([]byte("ok")) })
+	m.GET("/slow", func(c *mig.Context) error {
+		time.Sleep(20 * time.Millisecond)
+		return c.Raw([]byte("ok"))
+	})
+
+	for range 10 {
+		m.Mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fast", nil))
+	}
+	m.Mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assertEqual(t, 1, len(lines), "Only the slow request should be logged")
+	if !strings.Contains(lines[0], `"level":"WARN"`) || !strings.Contains(lines[0], `"path":"/slow"`) {
+		t.Errorf("unexpected log line: %s", lines[0])
+	}
+}
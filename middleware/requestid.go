@@ -9,11 +9,16 @@ import (
 )
 
 // RequestID middleware ensures every request has an ID.
-// It prefers the incoming X-Request-ID header, otherwise generates a random ID.
+// It prefers the incoming X-Request-ID header, then an ID already set by earlier
+// middleware (such as the trace ID set by tracing.Middleware), otherwise
+// generates a random ID.
 func RequestID() mig.MiddlewareFunc {
 	return func(next mig.Handler) mig.Handler {
 		return func(c *mig.Context) error {
 			id := c.Request.Header.Get(mig.RequestIDHeader)
+			if id == "" && c.RequestID() != "" {
+				return next(c)
+			}
 			if id == "" {
 				var b [8]byte
 				if _, err := io.ReadFull(rand.Reader, b[:]); err == nil {
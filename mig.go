@@ -3,6 +3,7 @@ package mig
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -13,8 +14,9 @@ import (
 	"os"
 	"os/signal"
 	"runtime/debug"
-	"strings"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
@@ -26,13 +28,61 @@ type Mig struct {
 	BindTo string
 	// ErrorHandler is used to process any errors during requests.
 	// By default, DefaultErrorHandler() is used
-	ErrorHandler    HTTPErrorHandler
-	Logger          *slog.Logger
-	Renderer        Renderer
+	ErrorHandler HTTPErrorHandler
+	Logger       *slog.Logger
+	Renderer     Renderer
+	// JSON configures JSON responses written by Context.
+	JSON JSONOptions
+	// Codecs are the encodings Context.Render chooses from, in order of
+	// preference. Default is JSON, XML, plain text and form.
+	Codecs          []Codec
 	pool            sync.Pool
+	routes          []*Route
 	http            *http.Server
 	ctx             context.Context
 	ShutdownTimeout time.Duration
+	// DrainPeriod is waited at the beginning of Shutdown, after ShuttingDown
+	// starts reporting true but before the server stops accepting requests.
+	// It gives load balancers polling a readiness endpoint time to take the
+	// instance out of rotation. Default is 0 (no delay).
+	DrainPeriod time.Duration
+	// HookTimeout limits each lifecycle hook (see Hook). It defaults to, and
+	// can not exceed, ShutdownTimeout.
+	HookTimeout  time.Duration
+	hooks        lifecycleHooks
+	lnMu         sync.Mutex
+	listeners    []net.Listener
+	shuttingDown atomic.Bool
+	shutdownOnce sync.Once
+	shutdownErr  error
+	// GracefulRestart makes Run restart the process on SIGHUP or SIGUSR2
+	// without dropping connections (see Restart). Unix only. Under systemd,
+	// use systemd.EnableNotify and NotifyAccess=all so that the unit follows
+	// the new process.
+	GracefulRestart bool
+	// RestartTimeout limits how long Restart waits for the new process.
+	RestartTimeout time.Duration
+	inheritOnce    sync.Once
+	inherited      []net.Listener
+	restartReady   *os.File
+	// TLSConfig is the base TLS configuration for StartTLS and RunTLS.
+	TLSConfig *tls.Config
+	// H2C enables HTTP/2 over cleartext connections (with prior knowledge)
+	// in addition to HTTP/1. Requires Go 1.24 or newer.
+	H2C   bool
+	HTTP2 HTTP2Options
+	// ProxyProtocol, if set, makes all listeners accept PROXY protocol
+	// headers from trusted load balancers.
+	ProxyProtocol *ProxyProtocol
+	// MaxConnections caps the number of open connections across all
+	// listeners; further connections wait in the listen backlog. Idle
+	// keep-alive connections count too, see IdleTimeout. Zero is unlimited.
+	MaxConnections int
+	// Upload limits uploads read by Context.FormFile and FormFiles.
+	Upload UploadOptions
+	// MaxHeaderBytes limits the size of request headers. Zero uses the
+	// net/http default of 1 MB.
+	MaxHeaderBytes int
 	// Request
 	ReadTimeout       time.Duration
 	ReadHeaderTimeout time.Duration
@@ -46,6 +96,15 @@ type (
 	HTTPErrorHandler func(error, *Context)
 )
 
+// WrapHandler adapts a standard http.Handler to a Handler, so it can be
+// registered on a RouteGroup and run behind the group's middleware.
+func WrapHandler(h http.Handler) Handler {
+	return func(c *Context) error {
+		h.ServeHTTP(c.Response, c.Request)
+		return nil
+	}
+}
+
 // Renderer is an interface for rendering templates.
 type Renderer interface {
 	Render(io.Writer, string, any) error
@@ -85,6 +144,7 @@ func New(ctx context.Context) *Mig {
 		ReadHeaderTimeout: 5 * time.Second,
 		WriteTimeout:      30 * time.Second,
 		IdleTimeout:       75 * time.Second,
+		RestartTimeout:    30 * time.Second,
 	}
 	m.RouteGroup = RouteGroup{
 		Mig: &m,
@@ -102,6 +162,7 @@ func New(ctx context.Context) *Mig {
 		},
 	}
 	m.ErrorHandler = m.DefaultErrorHandler
+	m.Codecs = []Codec{JSONCodec{}, XMLCodec{}, TextCodec{}, FormCodec{}}
 	// m.Renderer = &DefaultRenderer{}
 	m.http = &http.Server{
 		Addr:    ":8080",
@@ -113,63 +174,197 @@ func New(ctx context.Context) *Mig {
 
 // Start begins listening for HTTP requests in a separate goroutine.
 // It is a non-blocking method. Use the Shutdown method to stop the server.
+// If addr is not empty, a TCP listener is bound on it in addition to any
+// listeners added with Listen, ListenUnix or AddListener; all of them are
+// served concurrently. Use Addr to find out the bound address.
+// Lifecycle hooks registered with OnStart and OnListen run as part of Start.
 func (m *Mig) Start(addr string) error {
-	ln, err := net.Listen("tcp", addr)
-	if err != nil {
-		return err // bind failed
+	return m.start(addr, nil)
+}
+
+// start serves plain HTTP, or HTTPS if tlsConfig is not nil.
+func (m *Mig) start(addr string, tlsConfig *tls.Config) error {
+	if err := m.configureProtocols(); err != nil {
+		return err
+	}
+	if err := m.runStartHooks(); err != nil {
+		return m.abortStart(err)
+	}
+
+	if addr != "" {
+		if _, err := m.Listen("tcp", addr); err != nil {
+			return m.abortStart(err) // bind failed
+		}
+		m.http.Addr = addr
+	}
+	m.adoptInherited()
+
+	m.lnMu.Lock()
+	listeners := slices.Clone(m.listeners)
+	m.lnMu.Unlock()
+	if len(listeners) == 0 {
+		return m.abortStart(errNoListeners)
 	}
 
-	m.http.Addr = addr
 	m.http.ReadTimeout = m.ReadTimeout
 	m.http.ReadHeaderTimeout = m.ReadHeaderTimeout
 	m.http.WriteTimeout = m.WriteTimeout
 	m.http.IdleTimeout = m.IdleTimeout
+	m.http.TLSConfig = tlsConfig
 
-	m.Logger.Info("Server starting", "addr", m.http.Addr)
-
-	go func() {
-		if err := m.http.Serve(ln); err != http.ErrServerClosed {
-			m.Logger.Error("Server unexpectedly closed", "err", err)
+	if m.MaxConnections > 0 {
+		sem := make(chan struct{}, m.MaxConnections)
+		for i, ln := range listeners {
+			listeners[i] = &limitListener{Listener: ln, sem: sem, done: make(chan struct{})}
 		}
-	}()
+	}
+	if m.ProxyProtocol != nil {
+		for i, ln := range listeners {
+			pl, err := newProxyListener(ln, m.ProxyProtocol)
+			if err != nil {
+				return m.abortStart(err)
+			}
+			listeners[i] = pl
+		}
+	}
+
+	for _, ln := range listeners {
+		m.Logger.Info("Server starting", "addr", ln.Addr().String(), "tls", tlsConfig != nil)
+
+		go func() {
+			var err error
+			if tlsConfig != nil {
+				err = m.http.ServeTLS(ln, "", "")
+			} else {
+				err = m.http.Serve(ln)
+			}
+			if err != http.ErrServerClosed {
+				m.Logger.Error("Server unexpectedly closed", "err", err)
+			}
+		}()
+	}
+
+	if err := m.runHooks(m.ctx, "listen", m.hooks.listen, false, true); err != nil {
+		return errors.Join(err, m.Shutdown())
+	}
+	m.notifyRestartReady()
 	return nil
 }
 
+// abortStart runs the OnStopped hooks of the OnStart hooks that ran after a
+// failed Start, so that the resources they opened are released.
+func (m *Mig) abortStart(err error) error {
+	m.closeListeners()
+	return errors.Join(err, m.runHooks(context.WithoutCancel(m.ctx), "stopped", m.startedStoppedHooks(), true, false))
+}
+
 // Shutdown gracefully stops the HTTP server.
 // It waits for the duration of ShutdownTimeout for existing connections to finish.
+// Lifecycle hooks registered with OnShutdown and OnStopped run as part of
+// Shutdown; their errors are joined with the server shutdown error.
+// Only the first call shuts down; later calls wait for it and return its result.
 func (m *Mig) Shutdown() error {
+	m.shutdownOnce.Do(func() { m.shutdownErr = m.shutdown() })
+	return m.shutdownErr
+}
+
+func (m *Mig) shutdown() error {
 	m.Logger.Info("Server shutting down...")
+	m.shuttingDown.Store(true)
+
+	if m.DrainPeriod > 0 {
+		m.Logger.Info("Draining connections", "period", m.DrainPeriod)
+		t := time.NewTimer(m.DrainPeriod)
+		select {
+		case <-t.C:
+		case <-m.ctx.Done():
+			t.Stop()
+		}
+	}
+
+	// Teardown hooks must run even if the parent context is already done.
+	hookCtx := context.WithoutCancel(m.ctx)
+	hookErr := m.runHooks(hookCtx, "shutdown", m.hooks.shutdown, true, false)
 
 	shutdownCtx, cancel := context.WithTimeout(m.ctx, m.ShutdownTimeout)
 	defer cancel()
 
-	if err := m.http.Shutdown(shutdownCtx); err != nil {
+	err := m.http.Shutdown(shutdownCtx)
+	if err != nil {
 		m.Logger.Error("Server forced to shutdown", "err", err)
-		return err
 	}
-	m.Logger.Info("Server gracefully stopped.")
-	return nil
+
+	hookErr = errors.Join(hookErr, m.runHooks(hookCtx, "stopped", m.hooks.stopped, true, false))
+	if hookErr != nil {
+		m.Logger.Error("Lifecycle hooks failed", "err", hookErr)
+	}
+	if err == nil && hookErr == nil {
+		m.Logger.Info("Server gracefully stopped.")
+	}
+	return errors.Join(err, hookErr)
+}
+
+// ShuttingDown reports whether Shutdown has been called. Readiness checks
+// use it to fail while the server drains.
+func (m *Mig) ShuttingDown() bool {
+	return m.shuttingDown.Load()
 }
 
 // Run starts the server, blocks until an OS signal is received, and then
 // performs a graceful shutdown. This is the simplest way to run the server.
+// With GracefulRestart, SIGHUP and SIGUSR2 start a new process that takes
+// over the listeners before this one shuts down; if the restart fails, the
+// server keeps running.
 func (m *Mig) Run(addr string) error {
-	if err := m.Start(addr); err != nil {
+	return m.run(func() error { return m.Start(addr) })
+}
+
+func (m *Mig) run(start func() error) error {
+	if err := start(); err != nil {
 		return err
 	}
 
 	ctx, stop := signal.NotifyContext(m.ctx, os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	<-ctx.Done()
+	restart := make(chan os.Signal, 1)
+	if m.GracefulRestart && len(restartSignals) > 0 {
+		signal.Notify(restart, restartSignals...)
+		defer signal.Stop(restart)
+	}
 
-	return m.Shutdown()
+	for {
+		select {
+		case <-ctx.Done():
+			return m.Shutdown()
+		case sig := <-restart:
+			m.Logger.Info("Restarting", "signal", sig.String())
+			if err := m.Restart(); err != nil {
+				m.Logger.Error("Restart failed", "err", err)
+				continue
+			}
+			return m.Shutdown()
+		}
+	}
+}
+
+// Routes returns all routes registered so far, in registration order.
+func (m *Mig) Routes() []*Route {
+	return m.routes
 }
 
+// Execute runs handler for the request with a pooled Context and handles
+// any returned error or panic with ErrorHandler.
 func (m *Mig) Execute(handler Handler, rw http.ResponseWriter, r *http.Request) {
+	m.execute(nil, handler, rw, r)
+}
+
+func (m *Mig) execute(route *Route, handler Handler, rw http.ResponseWriter, r *http.Request) {
 	cont, _ := m.pool.Get().(*Context)
 	cont.Reset(r, rw)
+	cont.route = route
 	defer m.pool.Put(cont)
+	defer cont.runCleanups()
 
 	defer func() {
 		if rec := recover(); rec != nil {
@@ -240,7 +435,8 @@ func (m *Mig) DefaultErrorHandler(err error, ctx *Context) {
 		return
 	}
 
-	if strings.Contains(ctx.Request.Header.Get("Accept"), "application/json") {
+	// Plain text unless the client prefers JSON.
+	if NegotiateContentType(ctx.Request.Header.Get("Accept"), "text/plain", "application/json") == "application/json" {
 		ctx.Response.Header().Set("Content-Type", "application/json; charset=utf-8")
 		ctx.Response.WriteHeader(e.Code)
 		// Public-facing error payload
@@ -0,0 +1,323 @@
+package mig
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// MediaRange is one entry of an Accept header, e.g. "text/*;q=0.5".
+type MediaRange struct {
+	Type    string // "*" for any
+	Subtype string // "*" for any
+	Q       float64
+}
+
+// match reports how specifically r matches the media type: 2 for an exact
+// match, 1 for type/*, 0 for */* and -1 if it does not match.
+func (r MediaRange) match(typ, sub string) int {
+	switch {
+	case r.Type == "*":
+		return 0
+	case r.Type != typ:
+		return -1
+	case r.Subtype == "*":
+		return 1
+	case r.Subtype == sub:
+		return 2
+	}
+	return -1
+}
+
+// ParseAccept parses an Accept header into media ranges, in header order.
+// Media type parameters other than q are ignored; malformed entries are
+// skipped.
+func ParseAccept(header string) []MediaRange {
+	var ranges []MediaRange
+	for _, entry := range strings.Split(header, ",") {
+		mt, params, _ := strings.Cut(entry, ";")
+		typ, sub, ok := strings.Cut(strings.ToLower(strings.TrimSpace(mt)), "/")
+		if !ok {
+			if typ != "*" {
+				continue
+			}
+			sub = "*"
+		}
+		if typ == "" || sub == "" || (typ == "*" && sub != "*") {
+			continue
+		}
+		r := MediaRange{Type: typ, Subtype: sub, Q: 1}
+		for _, p := range strings.Split(params, ";") {
+			k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+			if strings.TrimSpace(k) != "q" {
+				continue
+			}
+			q, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil || q < 0 || q > 1 {
+				q = 0
+			}
+			r.Q = q
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// NegotiateContentType returns the offer the Accept header prefers, or ""
+// if none is acceptable. Offers with equal quality are ordered by how
+// specifically they were matched, then by the position of the matching
+// range in the header, then by their order in offers. An empty header
+// accepts the first offer.
+func NegotiateContentType(accept string, offers ...string) string {
+	if i := negotiate(accept, offers); i >= 0 {
+		return offers[i]
+	}
+	return ""
+}
+
+func negotiate(accept string, offers []string) int {
+	if len(offers) == 0 {
+		return -1
+	}
+	ranges := ParseAccept(accept)
+	if len(ranges) == 0 {
+		return 0
+	}
+	best, bestQ, bestSpec, bestPos := -1, 0.0, -1, 0
+	for i, offer := range offers {
+		mt, _, _ := strings.Cut(offer, ";")
+		typ, sub, _ := strings.Cut(strings.ToLower(strings.TrimSpace(mt)), "/")
+		// The most specific matching range decides the quality.
+		q, spec, pos := 0.0, -1, 0
+		for j, r := range ranges {
+			if s := r.match(typ, sub); s > spec {
+				q, spec, pos = r.Q, s, j
+			}
+		}
+		if spec < 0 || q <= 0 {
+			continue
+		}
+		if best < 0 || q > bestQ || q == bestQ && (spec > bestSpec || spec == bestSpec && pos < bestPos) {
+			best, bestQ, bestSpec, bestPos = i, q, spec, pos
+		}
+	}
+	return best
+}
+
+// Negotiate calls the function registered for the media type the client
+// prefers, e.g.
+//
+//	c.Negotiate(map[string]func() error{
+//		"text/html":        func() error { return c.View("user.html", u) },
+//		"application/json": func() error { return c.JSON(u) },
+//	})
+//
+// Media types equally acceptable to the client are picked in alphabetical
+// order. If none is acceptable, it returns a 406 Not Acceptable error.
+func (c *Context) Negotiate(offers map[string]func() error) error {
+	c.Response.Header().Add("Vary", "Accept")
+	types := make([]string, 0, len(offers))
+	for t := range offers {
+		types = append(types, t)
+	}
+	slices.Sort(types)
+	i := negotiate(c.Request.Header.Get("Accept"), types)
+	if i < 0 {
+		return NewHTTPError(http.StatusNotAcceptable)
+	}
+	return offers[types[i]]()
+}
+
+// Render writes v with the status code, encoded by the codec in
+// Mig.Codecs that the client's Accept header prefers among those that can
+// encode v; equally acceptable codecs are picked in registration order. If
+// encoding fails, the next acceptable codec is tried, and the error is
+// returned if none is left. If no codec is acceptable, it returns a
+// 406 Not Acceptable error.
+func (c *Context) Render(code int, v any) error {
+	var codecs []Codec
+	for _, codec := range c.Mig.Codecs {
+		if codec.CanEncode(v) {
+			codecs = append(codecs, codec)
+		}
+	}
+	c.Response.Header().Add("Vary", "Accept")
+	accept := c.Request.Header.Get("Accept")
+
+	var buf bytes.Buffer
+	var encodeErr error
+	for len(codecs) > 0 {
+		types := make([]string, len(codecs))
+		for i, codec := range codecs {
+			types[i] = codec.ContentType()
+		}
+		i := negotiate(accept, types)
+		if i < 0 {
+			break
+		}
+		buf.Reset()
+		if cc, ok := codecs[i].(contextCodec); ok {
+			encodeErr = cc.encodeFor(c, &buf, v)
+		} else {
+			encodeErr = codecs[i].Encode(&buf, v)
+		}
+		if encodeErr != nil {
+			codecs = slices.Delete(codecs, i, i+1)
+			continue
+		}
+		c.Response.Header().Set("Content-Type", types[i])
+		c.Response.WriteHeader(code)
+		_, err := c.Response.Write(buf.Bytes())
+		return err
+	}
+	if encodeErr != nil {
+		return encodeErr
+	}
+	return NewHTTPError(http.StatusNotAcceptable)
+}
+
+// Codec encodes values in one media type for Context.Render.
+type Codec interface {
+	// ContentType returns the Content-Type of the encoded value, e.g.
+	// "application/json; charset=utf-8".
+	ContentType() string
+	// CanEncode reports whether the codec supports values like v. Render
+	// skips codecs that do not.
+	CanEncode(v any) bool
+	Encode(w io.Writer, v any) error
+}
+
+// contextCodec is implemented by codecs whose output depends on the request
+// and Mig configuration.
+type contextCodec interface {
+	encodeFor(c *Context, buf *bytes.Buffer, v any) error
+}
+
+// RegisterCodec adds a codec to Mig.Codecs, replacing a codec for the same
+// media type.
+func (m *Mig) RegisterCodec(codec Codec) {
+	mt, _, _ := mime.ParseMediaType(codec.ContentType())
+	for i, c := range m.Codecs {
+		if t, _, _ := mime.ParseMediaType(c.ContentType()); t == mt {
+			m.Codecs[i] = codec
+			return
+		}
+	}
+	m.Codecs = append(m.Codecs, codec)
+}
+
+// JSONCodec encodes values with encoding/json. Render formats its output
+// like Context.JSON, according to Mig.JSON.
+type JSONCodec struct{}
+
+func (JSONCodec) ContentType() string { return "application/json; charset=utf-8" }
+
+func (JSONCodec) CanEncode(v any) bool {
+	switch reflect.Indirect(reflect.ValueOf(v)).Kind() {
+	case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		return false
+	}
+	return true
+}
+
+func (JSONCodec) Encode(w io.Writer, v any) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func (JSONCodec) encodeFor(c *Context, buf *bytes.Buffer, v any) error {
+	return c.encodeJSON(buf, v)
+}
+
+// XMLCodec encodes values with encoding/xml.
+type XMLCodec struct{}
+
+func (XMLCodec) ContentType() string { return "application/xml; charset=utf-8" }
+
+// CanEncode reports false for maps and nil, which encoding/xml can not encode.
+func (XMLCodec) CanEncode(v any) bool {
+	switch reflect.Indirect(reflect.ValueOf(v)).Kind() {
+	case reflect.Invalid, reflect.Map, reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return false
+	}
+	return true
+}
+
+func (XMLCodec) Encode(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(v)
+}
+
+// TextCodec writes strings, byte slices, errors and fmt.Stringers as they
+// are. It does not encode other values.
+type TextCodec struct{}
+
+func (TextCodec) ContentType() string { return "text/plain; charset=utf-8" }
+
+func (TextCodec) CanEncode(v any) bool {
+	switch v.(type) {
+	case string, []byte, error, fmt.Stringer:
+		return true
+	}
+	return false
+}
+
+func (TextCodec) Encode(w io.Writer, v any) error {
+	var err error
+	switch v := v.(type) {
+	case string:
+		_, err = io.WriteString(w, v)
+	case []byte:
+		_, err = w.Write(v)
+	case error:
+		_, err = io.WriteString(w, v.Error())
+	case fmt.Stringer:
+		_, err = io.WriteString(w, v.String())
+	default:
+		return fmt.Errorf("mig: TextCodec can not encode %T", v)
+	}
+	return err
+}
+
+// FormCodec encodes url.Values and string maps as
+// application/x-www-form-urlencoded.
+type FormCodec struct{}
+
+func (FormCodec) ContentType() string { return "application/x-www-form-urlencoded" }
+
+func (FormCodec) CanEncode(v any) bool {
+	switch v.(type) {
+	case url.Values, map[string][]string, map[string]string:
+		return true
+	}
+	return false
+}
+
+func (FormCodec) Encode(w io.Writer, v any) error {
+	var values url.Values
+	switch v := v.(type) {
+	case url.Values:
+		values = v
+	case map[string][]string:
+		values = v
+	case map[string]string:
+		values = url.Values{}
+		for k, s := range v {
+			values.Set(k, s)
+		}
+	default:
+		return fmt.Errorf("mig: FormCodec can not encode %T", v)
+	}
+	_, err := io.WriteString(w, values.Encode())
+	return err
+}
@@ -0,0 +1,136 @@
+package mig_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/levmv/mig"
+)
+
+func TestNegotiateContentType(t *testing.T) {
+	offers := []string{"application/json", "application/xml", "text/html"}
+	testCases := []struct {
+		accept string
+		want   string
+	}{
+		{"", "application/json"},
+		{"*/*", "application/json"},
+		{"text/html", "text/html"},
+		{"text/*", "text/html"},
+		{"application/xml;q=0.9, application/json;q=0.8", "application/xml"},
+		{"text/html;q=0.5, */*;q=0.8", "application/json"},
+		{"application/*;q=0.5, application/xml", "application/xml"},
+		{"application/xml, application/json", "application/xml"},
+		{"application/json;q=0, */*", "application/xml"},
+		{"image/png", ""},
+		{"garbage, text/html;q=0.1", "text/html"},
+	}
+	for _, tc := range testCases {
+		assertEqual(t, tc.want, mig.NegotiateContentType(tc.accept, offers...), "Accept: "+tc.accept)
+	}
+}
+
+// noJSON can only be encoded as XML.
+type noJSON struct {
+	Name string `xml:"name"`
+}
+
+func (noJSON) MarshalJSON() ([]byte, error) { return nil, errors.New("no JSON") }
+
+func TestContext_Render(t *testing.T) {
+	m := mig.New(context.Background())
+	restoreLogger := setupSilentLogger(m)
+	defer restoreLogger()
+
+	type user struct {
+		Name string `json:"name" xml:"name"`
+	}
+	m.GET("/user", func(c *mig.Context) error {
+		return c.Render(http.StatusCreated, user{Name: "Ann"})
+	})
+	m.GET("/form", func(c *mig.Context) error {
+		return c.Render(http.StatusOK, url.Values{"a": {"1"}, "b": {"x y"}})
+	})
+	m.GET("/map", func(c *mig.Context) error {
+		return c.Render(http.StatusOK, map[string]int{"a": 1})
+	})
+	m.GET("/text", func(c *mig.Context) error {
+		return c.Render(http.StatusOK, errors.New("boom"))
+	})
+	m.GET("/nojson", func(c *mig.Context) error {
+		return c.Render(http.StatusOK, noJSON{Name: "Ann"})
+	})
+	m.GET("/negotiate", func(c *mig.Context) error {
+		return c.Negotiate(map[string]func() error{
+			"text/html":        func() error { return c.HTML("<b>Ann</b>") },
+			"application/json": func() error { return c.JSON(user{Name: "Ann"}) },
+		})
+	})
+
+	testCases := []struct {
+		target, accept string
+		status         int
+		contentType    string
+		body           string
+	}{
+		{"/user", "", http.StatusCreated, "application/json; charset=utf-8", "{\"name\":\"Ann\"}"},
+		{"/user", "application/xml", http.StatusCreated, "application/xml; charset=utf-8", "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<user><name>Ann</name></user>"},
+		{"/user", "text/plain", http.StatusNotAcceptable, "text/plain; charset=utf-8", "Not Acceptable\n"},
+		{"/user", "application/x-www-form-urlencoded", http.StatusNotAcceptable, "text/plain; charset=utf-8", "Not Acceptable\n"},
+		{"/user", "image/png", http.StatusNotAcceptable, "text/plain; charset=utf-8", "Not Acceptable\n"},
+		{"/form", "application/x-www-form-urlencoded", http.StatusOK, "application/x-www-form-urlencoded", "a=1&b=x+y"},
+		{"/map", "application/xml", http.StatusNotAcceptable, "text/plain; charset=utf-8", "Not Acceptable\n"},
+		{"/map", "application/xml, application/json;q=0.5", http.StatusOK, "application/json; charset=utf-8", "{\"a\":1}"},
+		{"/text", "text/plain", http.StatusOK, "text/plain; charset=utf-8", "boom"},
+		{"/nojson", "application/json, application/xml;q=0.5", http.StatusOK, "application/xml; charset=utf-8", "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<noJSON><name>Ann</name></noJSON>"},
+		{"/nojson", "application/json", http.StatusInternalServerError, "application/json; charset=utf-8", "{\"code\":500,\"message\":\"Internal Server Error\"}\n"},
+		{"/negotiate", "text/html,application/xhtml+xml,*/*;q=0.8", http.StatusOK, "text/html; charset=utf-8", "<b>Ann</b>"},
+		{"/negotiate", "*/*", http.StatusOK, "application/json; charset=utf-8", `{"name":"Ann"}`},
+		{"/negotiate", "text/csv", http.StatusNotAcceptable, "text/plain; charset=utf-8", "Not Acceptable\n"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.target+" "+tc.accept, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			rec := httptest.NewRecorder()
+			m.Mux.ServeHTTP(rec, req)
+			assertEqual(t, tc.status, rec.Code, "Status")
+			assertEqual(t, tc.contentType, rec.Header().Get("Content-Type"), "Content-Type")
+			assertEqual(t, tc.body, rec.Body.String(), "Body")
+			assertEqual(t, "Accept", rec.Header().Get("Vary"), "Vary")
+		})
+	}
+}
+
+func TestDefaultErrorHandler_Negotiation(t *testing.T) {
+	m := mig.New(context.Background())
+	restoreLogger := setupSilentLogger(m)
+	defer restoreLogger()
+	m.GET("/", func(c *mig.Context) error { return mig.NewHTTPError(http.StatusTeapot) })
+
+	testCases := []struct {
+		accept      string
+		contentType string
+	}{
+		{"", "text/plain; charset=utf-8"},
+		{"*/*", "text/plain; charset=utf-8"},
+		{"application/json", "application/json; charset=utf-8"},
+		{"application/json, text/plain, */*", "application/json; charset=utf-8"},
+		{"text/plain, application/json;q=0.9", "text/plain; charset=utf-8"},
+		{"application/*", "application/json; charset=utf-8"},
+	}
+	for _, tc := range testCases {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", tc.accept)
+		rec := httptest.NewRecorder()
+		m.Mux.ServeHTTP(rec, req)
+		assertEqual(t, http.StatusTeapot, rec.Code, "Status")
+		assertEqual(t, tc.contentType, rec.Header().Get("Content-Type"), "Accept: "+tc.accept)
+	}
+}
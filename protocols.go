@@ -0,0 +1,17 @@
+package mig
+
+import "time"
+
+// HTTP2Options tunes the HTTP/2 server. Zero fields use the net/http
+// defaults. The options require Go 1.24 or newer.
+type HTTP2Options struct {
+	MaxConcurrentStreams          int
+	MaxReadFrameSize              int
+	MaxReceiveBufferPerConnection int
+	MaxReceiveBufferPerStream     int
+	// SendPingTimeout is how long a connection may be idle before a PING is
+	// sent; PingTimeout is how long to wait for the reply before closing it.
+	SendPingTimeout  time.Duration
+	PingTimeout      time.Duration
+	WriteByteTimeout time.Duration
+}
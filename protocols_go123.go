@@ -0,0 +1,13 @@
+//go:build !go1.24
+
+package mig
+
+import "errors"
+
+func (m *Mig) configureProtocols() error {
+	m.http.MaxHeaderBytes = m.MaxHeaderBytes
+	if m.H2C || m.HTTP2 != (HTTP2Options{}) {
+		return errors.New("mig: H2C and HTTP2 options require Go 1.24 or newer")
+	}
+	return nil
+}
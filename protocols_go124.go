@@ -0,0 +1,30 @@
+//go:build go1.24
+
+package mig
+
+import "net/http"
+
+func (m *Mig) configureProtocols() error {
+	m.http.MaxHeaderBytes = m.MaxHeaderBytes
+	m.http.Protocols = nil
+	if m.H2C {
+		p := new(http.Protocols)
+		p.SetHTTP1(true)
+		p.SetHTTP2(true)
+		p.SetUnencryptedHTTP2(true)
+		m.http.Protocols = p
+	}
+	m.http.HTTP2 = nil
+	if o := m.HTTP2; o != (HTTP2Options{}) {
+		m.http.HTTP2 = &http.HTTP2Config{
+			MaxConcurrentStreams:          o.MaxConcurrentStreams,
+			MaxReadFrameSize:              o.MaxReadFrameSize,
+			MaxReceiveBufferPerConnection: o.MaxReceiveBufferPerConnection,
+			MaxReceiveBufferPerStream:     o.MaxReceiveBufferPerStream,
+			SendPingTimeout:               o.SendPingTimeout,
+			PingTimeout:                   o.PingTimeout,
+			WriteByteTimeout:              o.WriteByteTimeout,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+//go:build go1.24
+
+package mig_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/levmv/mig"
+)
+
+func TestH2C(t *testing.T) {
+	m := mig.New(context.Background())
+	restoreLogger := setupSilentLogger(m)
+	defer restoreLogger()
+
+	m.H2C = true
+	m.HTTP2.MaxConcurrentStreams = 10
+	m.MaxHeaderBytes = 1024
+	m.GET("/", func(c *mig.Context) error { return c.Raw([]byte(c.Protocol())) })
+	assertNoError(t, m.Start("127.0.0.1:0"), "Start failed")
+	defer m.Shutdown()
+	url := "http://" + m.Addr().String() + "/"
+
+	h2c := new(http.Protocols)
+	h2c.SetUnencryptedHTTP2(true)
+	h2cClient := &http.Client{Transport: &http.Transport{Protocols: h2c}}
+	assertEqual(t, "h2c", get(t, h2cClient, url), "Prior knowledge HTTP/2")
+	assertEqual(t, "http/1.1", get(t, http.DefaultClient, url), "HTTP/1.1 still served")
+
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("X-Large", strings.Repeat("a", 16<<10))
+	res, err := http.DefaultClient.Do(req)
+	assertNoError(t, err, "Request failed")
+	res.Body.Close()
+	assertEqual(t, http.StatusRequestHeaderFieldsTooLarge, res.StatusCode, "MaxHeaderBytes")
+}
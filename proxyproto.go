@@ -0,0 +1,236 @@
+package mig
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProxyProtocol enables parsing of HAProxy PROXY protocol (v1 and v2)
+// headers on all listeners, so that the request's RemoteAddr is the
+// original client rather than the load balancer.
+//
+// The header is optional: connections that don't start with one are served
+// as is. Headers are only accepted from trusted sources; from anywhere else
+// they are not parsed, and the request fails as malformed.
+type ProxyProtocol struct {
+	// TrustedCIDRs lists the networks of load balancers allowed to send
+	// PROXY headers, e.g. "10.0.0.0/8". Start fails if it is empty and
+	// TrustAll is not set.
+	TrustedCIDRs []string
+	// TrustAll accepts headers from every source, including unix socket
+	// peers. Use it only when the listeners can not be reached directly.
+	TrustAll bool
+	// HeaderTimeout limits reading the header. Default is 5 seconds.
+	HeaderTimeout time.Duration
+}
+
+type proxyListener struct {
+	net.Listener
+	trustAll bool
+	trusted  []netip.Prefix
+	timeout  time.Duration
+}
+
+var errNoTrustedProxies = errors.New("mig: proxy protocol: TrustedCIDRs is empty; set TrustAll to trust every source")
+
+func newProxyListener(ln net.Listener, cfg *ProxyProtocol) (net.Listener, error) {
+	if len(cfg.TrustedCIDRs) == 0 && !cfg.TrustAll {
+		return nil, errNoTrustedProxies
+	}
+	pl := &proxyListener{Listener: ln, trustAll: cfg.TrustAll, timeout: cfg.HeaderTimeout}
+	if pl.timeout <= 0 {
+		pl.timeout = 5 * time.Second
+	}
+	for _, s := range cfg.TrustedCIDRs {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("mig: proxy protocol: %w", err)
+		}
+		pl.trusted = append(pl.trusted, p.Masked())
+	}
+	return pl, nil
+}
+
+func (l *proxyListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil || !l.isTrusted(conn.RemoteAddr()) {
+		return conn, err
+	}
+	// The header is read lazily, in the connection's own goroutine, so a
+	// slow client does not block Accept.
+	return &proxyConn{Conn: conn, r: bufio.NewReader(conn), timeout: l.timeout}, nil
+}
+
+func (l *proxyListener) isTrusted(addr net.Addr) bool {
+	if l.trustAll {
+		return true
+	}
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	ip := tcp.AddrPort().Addr().Unmap()
+	for _, p := range l.trusted {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+type proxyConn struct {
+	net.Conn
+	r       *bufio.Reader
+	timeout time.Duration
+
+	once       sync.Once
+	err        error
+	remoteAddr net.Addr
+	localAddr  net.Addr
+}
+
+func (c *proxyConn) Read(b []byte) (int, error) {
+	c.once.Do(c.readHeader)
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.r.Read(b)
+}
+
+func (c *proxyConn) RemoteAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.remoteAddr != nil {
+		return c.remoteAddr
+	}
+	return c.Conn.RemoteAddr()
+}
+
+func (c *proxyConn) LocalAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.localAddr != nil {
+		return c.localAddr
+	}
+	return c.Conn.LocalAddr()
+}
+
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+func (c *proxyConn) readHeader() {
+	c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+	defer c.Conn.SetReadDeadline(time.Time{})
+
+	b, err := c.r.Peek(1)
+	if err != nil {
+		c.err = err
+		return
+	}
+	switch b[0] {
+	case 'P':
+		if b, _ := c.r.Peek(6); string(b) == "PROXY " {
+			c.err = c.readV1()
+		}
+	case '\r':
+		if b, _ := c.r.Peek(len(proxyV2Signature)); bytes.Equal(b, proxyV2Signature) {
+			c.err = c.readV2()
+		}
+	}
+	if c.err != nil {
+		c.err = fmt.Errorf("mig: proxy protocol: %w", c.err)
+	}
+}
+
+// readV1 parses "PROXY TCP4 192.0.2.1 192.0.2.2 56324 443\r\n".
+func (c *proxyConn) readV1() error {
+	var line []byte
+	for len(line) < 107 {
+		b, err := c.r.ReadByte()
+		if err != nil {
+			return err
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+	}
+	s, ok := strings.CutSuffix(string(line), "\r\n")
+	if !ok {
+		return errors.New("v1 header is too long or not terminated")
+	}
+	fields := strings.Split(s, " ")
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return fmt.Errorf("malformed v1 header %q", s)
+	}
+	src, err := parseAddrPort(fields[2], fields[4])
+	if err != nil {
+		return err
+	}
+	dst, err := parseAddrPort(fields[3], fields[5])
+	if err != nil {
+		return err
+	}
+	c.remoteAddr, c.localAddr = net.TCPAddrFromAddrPort(src), net.TCPAddrFromAddrPort(dst)
+	return nil
+}
+
+func parseAddrPort(ip, port string) (netip.AddrPort, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	return netip.AddrPortFrom(addr, uint16(p)), nil
+}
+
+// readV2 parses the binary header: the signature, version and command,
+// address family, payload length, addresses and optional TLVs.
+func (c *proxyConn) readV2() error {
+	var hdr [16]byte
+	if _, err := io.ReadFull(c.r, hdr[:]); err != nil {
+		return err
+	}
+	if hdr[12]>>4 != 2 {
+		return fmt.Errorf("unsupported version %d", hdr[12]>>4)
+	}
+	payload := make([]byte, binary.BigEndian.Uint16(hdr[14:16]))
+	if _, err := io.ReadFull(c.r, payload); err != nil {
+		return err
+	}
+	if hdr[12]&0x0f == 0 { // LOCAL: health checks from the proxy itself
+		return nil
+	}
+	var ipLen int
+	switch hdr[13] >> 4 {
+	case 1:
+		ipLen = 4
+	case 2:
+		ipLen = 16
+	default: // unix or unspecified addresses: keep the real ones
+		return nil
+	}
+	if len(payload) < 2*ipLen+4 {
+		return errors.New("v2 address block is too short")
+	}
+	srcIP, _ := netip.AddrFromSlice(payload[:ipLen])
+	dstIP, _ := netip.AddrFromSlice(payload[ipLen : 2*ipLen])
+	srcPort := binary.BigEndian.Uint16(payload[2*ipLen:])
+	dstPort := binary.BigEndian.Uint16(payload[2*ipLen+2:])
+	c.remoteAddr = net.TCPAddrFromAddrPort(netip.AddrPortFrom(srcIP, srcPort))
+	c.localAddr = net.TCPAddrFromAddrPort(netip.AddrPortFrom(dstIP, dstPort))
+	return nil
+}
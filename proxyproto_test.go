@@ -0,0 +1,109 @@
+package mig_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/levmv/mig"
+)
+
+func proxyRequest(t *testing.T, addr string, header []byte) (int, string) {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	assertNoError(t, err, "Dial failed")
+	defer conn.Close()
+	conn.Write(header)
+	io.WriteString(conn, "GET / HTTP/1.1\r\nHost: x\r\nConnection: close\r\n\r\n")
+	res, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		return 0, ""
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	return res.StatusCode, string(body)
+}
+
+func proxyV2Header(src, dst net.IP, srcPort, dstPort uint16) []byte {
+	h := []byte("\r\n\r\n\x00\r\nQUIT\n")
+	h = append(h, 0x21, 0x11) // version 2, PROXY; TCP over IPv4
+	h = binary.BigEndian.AppendUint16(h, 12+3)
+	h = append(h, src.To4()...)
+	h = append(h, dst.To4()...)
+	h = binary.BigEndian.AppendUint16(h, srcPort)
+	h = binary.BigEndian.AppendUint16(h, dstPort)
+	return append(h, 0x04, 0x00, 0x00) // an empty TLV, skipped
+}
+
+func TestProxyProtocol(t *testing.T) {
+	m := mig.New(context.Background())
+	restoreLogger := setupSilentLogger(m)
+	defer restoreLogger()
+
+	m.ProxyProtocol = &mig.ProxyProtocol{TrustedCIDRs: []string{"127.0.0.0/8"}}
+	m.GET("/", func(c *mig.Context) error {
+		return c.Raw([]byte(c.Request.RemoteAddr + " " + c.RealIP()))
+	})
+	assertNoError(t, m.Start("127.0.0.1:0"), "Start failed")
+	defer m.Shutdown()
+	addr := m.Addr().String()
+
+	testCases := []struct {
+		name   string
+		header []byte
+		status int
+		body   string
+	}{
+		{"v1 TCP4", []byte("PROXY TCP4 203.0.113.7 10.0.0.1 56324 443\r\n"), 200, "203.0.113.7:56324 203.0.113.7"},
+		{"v1 TCP6", []byte("PROXY TCP6 2001:db8::1 2001:db8::2 4000 443\r\n"), 200, "[2001:db8::1]:4000 2001:db8::1"},
+		{"v2 TCP4", proxyV2Header(net.ParseIP("198.51.100.9"), net.ParseIP("10.0.0.1"), 1234, 80), 200, "198.51.100.9:1234 198.51.100.9"},
+		{"v1 malformed", []byte("PROXY TCP4 nonsense\r\n"), 400, "400 Bad Request"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			status, body := proxyRequest(t, addr, tc.header)
+			assertEqual(t, tc.status, status, "Status")
+			assertEqual(t, tc.body, body, "Client address")
+		})
+	}
+
+	t.Run("no header", func(t *testing.T) {
+		status, body := proxyRequest(t, addr, nil)
+		assertEqual(t, 200, status, "Status")
+		assertEqual(t, "127.0.0.1", body[len(body)-len("127.0.0.1"):], "Connection address")
+	})
+}
+
+func TestProxyProtocol_UntrustedSource(t *testing.T) {
+	m := mig.New(context.Background())
+	restoreLogger := setupSilentLogger(m)
+	defer restoreLogger()
+
+	m.ProxyProtocol = &mig.ProxyProtocol{TrustedCIDRs: []string{"10.0.0.0/8"}}
+	m.GET("/", func(c *mig.Context) error { return c.Raw([]byte(c.RealIP())) })
+	assertNoError(t, m.Start("127.0.0.1:0"), "Start failed")
+	defer m.Shutdown()
+
+	status, _ := proxyRequest(t, m.Addr().String(), []byte("PROXY TCP4 203.0.113.7 10.0.0.1 56324 443\r\n"))
+	assertEqual(t, http.StatusBadRequest, status, "Header from untrusted source is not parsed")
+
+	status, body := proxyRequest(t, m.Addr().String(), nil)
+	assertEqual(t, http.StatusOK, status, "Plain request from untrusted source")
+	assertEqual(t, "127.0.0.1", body, "RealIP")
+}
+
+func TestProxyProtocol_RequiresTrustedSources(t *testing.T) {
+	m := mig.New(context.Background())
+	restoreLogger := setupSilentLogger(m)
+	defer restoreLogger()
+
+	m.ProxyProtocol = &mig.ProxyProtocol{}
+	if err := m.Start("127.0.0.1:0"); err == nil {
+		m.Shutdown()
+		t.Fatal("Start without TrustedCIDRs or TrustAll should fail")
+	}
+}
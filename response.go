@@ -39,3 +39,9 @@ func (w *Response) Status() int {
 func (w *Response) Written() int {
 	return w.written
 }
+
+// Unwrap returns the underlying ResponseWriter, so that
+// http.ResponseController can reach its Flush, Hijack and deadline methods.
+func (w *Response) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
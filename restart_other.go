@@ -0,0 +1,19 @@
+//go:build !unix
+
+package mig
+
+import (
+	"errors"
+	"os"
+)
+
+var restartSignals []os.Signal
+
+// Restart is not supported on this platform.
+func (m *Mig) Restart() error {
+	return errors.New("mig: restart is not supported on this platform")
+}
+
+func (m *Mig) loadInherited() {}
+
+func (m *Mig) notifyRestartReady() {}
@@ -0,0 +1,87 @@
+//go:build unix
+
+package mig_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/levmv/mig"
+)
+
+// TestGracefulRestart re-executes the test binary, which then runs this test
+// again in the role of the new process.
+func TestGracefulRestart(t *testing.T) {
+	if os.Getenv("MIG_RESTART_FDS") != "" {
+		runRestartedChild(t)
+		return
+	}
+
+	m := mig.New(context.Background())
+	restoreLogger := setupSilentLogger(m)
+	defer restoreLogger()
+
+	m.GET("/", func(c *mig.Context) error { return c.Raw([]byte("old")) })
+	var newPID int
+	m.OnRestart(func(_ context.Context, pid int) error {
+		newPID = pid
+		return nil
+	})
+	sockPath := filepath.Join(t.TempDir(), "mig.sock")
+	t.Setenv("MIG_TEST_SOCKET", sockPath)
+	_, err := m.ListenUnix(mig.UnixSocket{Path: sockPath})
+	assertNoError(t, err, "ListenUnix failed")
+	assertNoError(t, m.Start("127.0.0.1:0"), "Start failed")
+	url := "http://" + m.Addrs()[1].String()
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	assertEqual(t, "old", get(t, client, url+"/"), "Response before restart")
+
+	args := os.Args
+	os.Args = []string{args[0], "-test.run=^TestGracefulRestart$"}
+	err = m.Restart()
+	os.Args = args
+	assertNoError(t, err, "Restart failed")
+	if newPID == 0 || newPID == os.Getpid() {
+		t.Fatalf("OnRestart got pid %d", newPID)
+	}
+
+	assertNoError(t, m.Shutdown(), "Shutdown failed")
+	assertEqual(t, "new", get(t, client, url+"/"), "Response after restart")
+	unixClient := &http.Client{Transport: &http.Transport{
+		DisableKeepAlives: true,
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", sockPath)
+		},
+	}}
+	assertEqual(t, "new", get(t, unixClient, "http://unix/"), "Unix socket kept after restart")
+	get(t, client, url+"/stop")
+}
+
+func runRestartedChild(t *testing.T) {
+	m := mig.New(context.Background())
+	restoreLogger := setupSilentLogger(m)
+	defer restoreLogger()
+
+	stop := make(chan struct{})
+	m.GET("/", func(c *mig.Context) error { return c.Raw([]byte("new")) })
+	m.GET("/stop", func(c *mig.Context) error {
+		close(stop)
+		return c.Raw([]byte("stopping"))
+	})
+
+	// The socket path matches an inherited listener, so it is reused rather
+	// than bound again; the TCP listener is served without naming it.
+	_, err := m.ListenUnix(mig.UnixSocket{Path: os.Getenv("MIG_TEST_SOCKET")})
+	assertNoError(t, err, "ListenUnix with inherited socket failed")
+	assertNoError(t, m.Start(""), "Start with inherited listeners failed")
+	select {
+	case <-stop:
+	case <-time.After(10 * time.Second):
+	}
+	m.Shutdown()
+}
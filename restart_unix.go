@@ -0,0 +1,164 @@
+//go:build unix
+
+package mig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// restartFDsEnv tells a restarted process how many listeners it inherited.
+// They occupy file descriptors 3..3+n-1; the readiness pipe follows them.
+const restartFDsEnv = "MIG_RESTART_FDS"
+
+var restartSignals = []os.Signal{syscall.SIGHUP, syscall.SIGUSR2}
+
+// Restart starts a new copy of the running binary with the same arguments,
+// handing it the listening sockets, and waits until the new process has
+// started (its OnListen hooks ran). Then the OnRestart hooks run and the
+// caller shuts the current process down; Run does this when GracefulRestart
+// is set. If the new process fails to start within RestartTimeout, it is
+// killed and the current process keeps serving.
+func (m *Mig) Restart() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	m.lnMu.Lock()
+	var files []*os.File
+	for _, ln := range m.listeners {
+		f, err := listenerFile(ln)
+		if err != nil {
+			m.lnMu.Unlock()
+			closeFiles(files)
+			return err
+		}
+		files = append(files, f)
+	}
+	m.lnMu.Unlock()
+	defer closeFiles(files)
+
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer readyR.Close()
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = append(os.Environ(), restartFDsEnv+"="+strconv.Itoa(len(files)))
+	cmd.ExtraFiles = append(files, readyW)
+	err = cmd.Start()
+	readyW.Close()
+	if err != nil {
+		return err
+	}
+
+	ready := make(chan error, 1)
+	go func() {
+		// Read returns EOF if the child exits without signalling readiness.
+		_, err := readyR.Read(make([]byte, 1))
+		ready <- err
+	}()
+
+	timer := time.NewTimer(m.RestartTimeout)
+	defer timer.Stop()
+	select {
+	case err = <-ready:
+		if err == nil {
+			err = m.runRestartHooks(cmd.Process.Pid)
+		}
+		if err == nil {
+			m.Logger.Info("Restarted", "pid", cmd.Process.Pid)
+			// The new process now owns the sockets; don't remove unix
+			// socket files when our listeners are closed.
+			m.keepSocketFiles()
+			return cmd.Process.Release()
+		}
+		if err == io.EOF {
+			err = errors.New("new process exited before it was ready")
+		}
+	case <-timer.C:
+		err = errors.New("new process was not ready in time")
+	case <-m.ctx.Done():
+		err = m.ctx.Err()
+	}
+	cmd.Process.Kill()
+	go cmd.Wait()
+	return fmt.Errorf("mig: restart: %w", err)
+}
+
+func (m *Mig) runRestartHooks(pid int) error {
+	for i, h := range m.hooks.restart {
+		ctx, cancel := context.WithTimeout(m.ctx, m.hookTimeout())
+		err := h(ctx, pid)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("restart hook #%d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func listenerFile(ln net.Listener) (*os.File, error) {
+	if f, ok := ln.(interface{ File() (*os.File, error) }); ok {
+		return f.File()
+	}
+	return nil, fmt.Errorf("mig: restart: listener %s can not be passed to a new process", ln.Addr())
+}
+
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		f.Close()
+	}
+}
+
+func (m *Mig) keepSocketFiles() {
+	m.lnMu.Lock()
+	defer m.lnMu.Unlock()
+	for _, ln := range m.listeners {
+		if ul, ok := ln.(*net.UnixListener); ok {
+			ul.SetUnlinkOnClose(false)
+		}
+	}
+}
+
+// loadInherited picks up listeners passed by Restart in the parent process.
+func (m *Mig) loadInherited() {
+	m.inheritOnce.Do(func() {
+		n, err := strconv.Atoi(os.Getenv(restartFDsEnv))
+		if err != nil || n <= 0 {
+			return
+		}
+		os.Unsetenv(restartFDsEnv)
+		for i := range n {
+			f := os.NewFile(uintptr(3+i), "listener")
+			ln, err := net.FileListener(f)
+			f.Close()
+			if err != nil {
+				m.Logger.Error("Failed to inherit listener", "fd", 3+i, "err", err)
+				continue
+			}
+			m.inherited = append(m.inherited, ln)
+		}
+		m.restartReady = os.NewFile(uintptr(3+n), "ready")
+	})
+}
+
+// notifyRestartReady tells the parent process that this process is serving.
+func (m *Mig) notifyRestartReady() {
+	if m.restartReady != nil {
+		m.restartReady.Write([]byte{1})
+		m.restartReady.Close()
+		m.restartReady = nil
+	}
+}
@@ -0,0 +1,69 @@
+package mig
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// SPAOptions configures RouteGroup.SPA.
+type SPAOptions struct {
+	// Index is the application shell served for client-side routes.
+	// Default is "index.html".
+	Index string
+	// IsAsset reports whether a missing path refers to a file rather than a
+	// client-side route, so it gets a 404 instead of the shell. By default,
+	// paths whose last segment has an extension, e.g. "logo.svg", are assets.
+	IsAsset func(name string) bool
+	// ExcludePrefixes lists URL path prefixes, e.g. "/api/", that never get
+	// the shell, so unknown API endpoints still answer 404.
+	ExcludePrefixes []string
+	// Static configures how existing files are served and cached.
+	Static StaticOptions
+}
+
+// SPA serves a single-page application from fsys under prefix: existing
+// files are served like Static, missing assets answer 404, and every other
+// GET request gets the index.html shell, with no-cache so that new
+// deployments are picked up. Routes registered on more specific patterns,
+// such as an API under the same prefix, take precedence.
+func (rg *RouteGroup) SPA(prefix string, fsys fs.FS, opts SPAOptions) *Route {
+	if opts.Index == "" {
+		opts.Index = "index.html"
+	}
+	if opts.IsAsset == nil {
+		opts.IsAsset = func(name string) bool { return path.Ext(name) != "" }
+	}
+	s := newStaticHandler(fsys, opts.Static)
+
+	return rg.GET(strings.TrimSuffix(prefix, "/")+"/{path...}", func(c *Context) error {
+		name := c.PathValue("path")
+		if name != "" && fs.ValidPath(name) {
+			if f, fi, err := s.open(name); err == nil {
+				defer f.Close()
+				if !fi.IsDir() {
+					cacheControl := s.cacheControl(name)
+					if name == opts.Index {
+						cacheControl = "no-cache"
+					}
+					return s.serveFile(c, name, f, fi, cacheControl)
+				}
+			}
+			if opts.IsAsset(name) {
+				return ErrNotFound
+			}
+		}
+		for _, p := range opts.ExcludePrefixes {
+			if strings.HasPrefix(c.Request.URL.Path, p) {
+				return ErrNotFound
+			}
+		}
+
+		f, fi, err := s.open(opts.Index)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return s.serveFile(c, opts.Index, f, fi, "no-cache")
+	})
+}
@@ -0,0 +1,53 @@
+package mig_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/levmv/mig"
+)
+
+func TestSPA(t *testing.T) {
+	m := mig.New(context.Background())
+	restoreLogger := setupSilentLogger(m)
+	defer restoreLogger()
+
+	m.GET("/api/users", func(c *mig.Context) error { return c.Raw([]byte("users")) })
+	m.SPA("/", fstest.MapFS{
+		"index.html":             {Data: []byte("shell")},
+		"favicon.ico":            {Data: []byte("icon")},
+		"assets/app.3f2a9c1b.js": {Data: []byte("app")},
+	}, mig.SPAOptions{
+		ExcludePrefixes: []string{"/api/"},
+		Static:          mig.StaticOptions{CacheControl: "public, max-age=600"},
+	})
+
+	testCases := []struct {
+		target       string
+		status       int
+		body         string
+		cacheControl string
+	}{
+		{"/", 200, "shell", "no-cache"},
+		{"/index.html", 200, "shell", "no-cache"},
+		{"/dashboard/settings", 200, "shell", "no-cache"},
+		{"/assets/", 200, "shell", "no-cache"},
+		{"/assets/app.3f2a9c1b.js", 200, "app", "public, max-age=31536000, immutable"},
+		{"/favicon.ico", 200, "icon", "public, max-age=600"},
+		{"/assets/missing.js", 404, "Not Found\n", ""},
+		{"/api/users", 200, "users", ""},
+		{"/api/unknown", 404, "Not Found\n", ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.target, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			m.Mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.target, nil))
+			assertEqual(t, tc.status, rec.Code, "Status")
+			assertEqual(t, tc.body, rec.Body.String(), "Body")
+			assertEqual(t, tc.cacheControl, rec.Header().Get("Cache-Control"), "Cache-Control")
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package mig
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// StaticOptions configures RouteGroup.Static. The zero value serves files
+// with revalidation caching, index.html for directories and no listings.
+type StaticOptions struct {
+	// Index is served for directory requests. Default is "index.html".
+	Index string
+	// Browse enables directory listings for directories without an index.
+	Browse bool
+	// CacheControl is sent with regular files. Default is "no-cache", so
+	// clients revalidate with the ETag or Last-Modified.
+	CacheControl string
+	// ImmutableCacheControl is sent with fingerprinted files. Default is
+	// "public, max-age=31536000, immutable".
+	ImmutableCacheControl string
+	// IsFingerprinted reports whether a file name contains a content hash.
+	// By default, names with 8 or more hex digits before the extension
+	// match, e.g. "app.3f2a9c1b.js" or "style-0d4e7a91c2.css".
+	IsFingerprinted func(name string) bool
+	// Precompressed serves "name.gz" instead of "name", if it exists and the
+	// client accepts gzip.
+	Precompressed bool
+}
+
+var fingerprintRe = regexp.MustCompile(`[.-][0-9a-f]{8,}\.[^./]+$`)
+
+// Static serves files from fsys under prefix, e.g.
+// m.Static("/assets", os.DirFS("public"), mig.StaticOptions{}).
+// Responses carry ETag and Last-Modified headers and support conditional
+// and range requests. Missing files are reported as ErrNotFound through
+// the ErrorHandler.
+func (rg *RouteGroup) Static(prefix string, fsys fs.FS, opts StaticOptions) *Route {
+	h := newStaticHandler(fsys, opts)
+	return rg.GET(strings.TrimSuffix(prefix, "/")+"/{path...}", h.serve)
+}
+
+type staticHandler struct {
+	fsys  fs.FS
+	opts  StaticOptions
+	etags sync.Map // name -> content hash ETag, for files without a modtime
+}
+
+func newStaticHandler(fsys fs.FS, opts StaticOptions) *staticHandler {
+	if opts.Index == "" {
+		opts.Index = "index.html"
+	}
+	if opts.CacheControl == "" {
+		opts.CacheControl = "no-cache"
+	}
+	if opts.ImmutableCacheControl == "" {
+		opts.ImmutableCacheControl = "public, max-age=31536000, immutable"
+	}
+	if opts.IsFingerprinted == nil {
+		opts.IsFingerprinted = fingerprintRe.MatchString
+	}
+	return &staticHandler{fsys: fsys, opts: opts}
+}
+
+func (s *staticHandler) serve(c *Context) error {
+	return s.serveName(c, c.PathValue("path"))
+}
+
+func (s *staticHandler) serveName(c *Context, name string) error {
+	name = strings.TrimSuffix(name, "/")
+	if name == "" {
+		name = "."
+	}
+	if !fs.ValidPath(name) {
+		return ErrNotFound
+	}
+	f, fi, err := s.open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if fi.IsDir() {
+		if p := c.Request.URL.Path; !strings.HasSuffix(p, "/") {
+			// Relative links in the index must resolve inside the directory.
+			return c.Redirect(http.StatusMovedPermanently, p+"/")
+		}
+		index := path.Join(name, s.opts.Index)
+		if idx, idxInfo, err := s.open(index); err == nil && !idxInfo.IsDir() {
+			defer idx.Close()
+			return s.serveFile(c, index, idx, idxInfo, s.cacheControl(index))
+		}
+		if s.opts.Browse {
+			return s.list(c, name)
+		}
+		return ErrNotFound
+	}
+	return s.serveFile(c, name, f, fi, s.cacheControl(name))
+}
+
+func (s *staticHandler) open(name string) (fs.File, fs.FileInfo, error) {
+	f, err := s.fsys.Open(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, ErrNotFound
+		}
+		if errors.Is(err, fs.ErrPermission) {
+			return nil, nil, NewHTTPError(http.StatusForbidden)
+		}
+		return nil, nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return f, fi, nil
+}
+
+func (s *staticHandler) cacheControl(name string) string {
+	if s.opts.IsFingerprinted(path.Base(name)) {
+		return s.opts.ImmutableCacheControl
+	}
+	return s.opts.CacheControl
+}
+
+func (s *staticHandler) serveFile(c *Context, name string, f fs.File, fi fs.FileInfo, cacheControl string) error {
+	h := c.Response.Header()
+	h.Set("Cache-Control", cacheControl)
+	ctype := mime.TypeByExtension(path.Ext(name))
+
+	if s.opts.Precompressed {
+		h.Add("Vary", "Accept-Encoding")
+		if acceptsGzip(c.Request) {
+			if gz, gzInfo, err := s.open(name + ".gz"); err == nil {
+				defer gz.Close()
+				if !gzInfo.IsDir() {
+					if ctype == "" {
+						ctype = "application/octet-stream"
+					}
+					h.Set("Content-Encoding", "gzip")
+					name, f, fi = name+".gz", gz, gzInfo
+				}
+			}
+		}
+	}
+
+	if ctype != "" {
+		h.Set("Content-Type", ctype)
+	}
+	rs, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		rs = bytes.NewReader(data)
+	}
+	etag, err := s.etag(name, fi, rs)
+	if err != nil {
+		return err
+	}
+	h.Set("ETag", etag)
+
+	http.ServeContent(c.Response, c.Request, name, fi.ModTime(), rs)
+	return nil
+}
+
+// etag derives the ETag from the modification time and size, or from the
+// content for file systems without modification times, such as embed.FS.
+func (s *staticHandler) etag(name string, fi fs.FileInfo, rs io.ReadSeeker) (string, error) {
+	if !fi.ModTime().IsZero() {
+		return `"` + strconv.FormatInt(fi.ModTime().UnixNano(), 36) + "-" + strconv.FormatInt(fi.Size(), 36) + `"`, nil
+	}
+	key := name + "\x00" + strconv.FormatInt(fi.Size(), 10)
+	if etag, ok := s.etags.Load(key); ok {
+		return etag.(string), nil
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, rs); err != nil {
+		return "", err
+	}
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	etag := `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+	s.etags.Store(key, etag)
+	return etag, nil
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, v := range r.Header.Values("Accept-Encoding") {
+		for _, enc := range strings.Split(v, ",") {
+			enc, params, _ := strings.Cut(enc, ";")
+			if !strings.EqualFold(strings.TrimSpace(enc), "gzip") {
+				continue
+			}
+			q := 1.0
+			for _, p := range strings.Split(params, ";") {
+				if k, v, _ := strings.Cut(strings.TrimSpace(p), "="); k == "q" {
+					q, _ = strconv.ParseFloat(v, 64)
+				}
+			}
+			return q > 0
+		}
+	}
+	return false
+}
+
+func (s *staticHandler) list(c *Context, name string) error {
+	entries, err := fs.ReadDir(s.fsys, name)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	b.WriteString("<!doctype html>\n<meta name=\"viewport\" content=\"width=device-width\">\n<pre>\n")
+	for _, e := range entries {
+		n := e.Name()
+		if e.IsDir() {
+			n += "/"
+		}
+		fmt.Fprintf(&b, "<a href=\"%s\">%s</a>\n", html.EscapeString(pathEscape(n)), html.EscapeString(n))
+	}
+	b.WriteString("</pre>\n")
+	c.Response.Header().Set("Cache-Control", s.opts.CacheControl)
+	return c.HTML(b.String())
+}
+
+func pathEscape(name string) string {
+	return (&url.URL{Path: name}).String()
+}
@@ -0,0 +1,120 @@
+package mig_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/levmv/mig"
+)
+
+func TestStatic(t *testing.T) {
+	m := mig.New(context.Background())
+	restoreLogger := setupSilentLogger(m)
+	defer restoreLogger()
+
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"index.html":         {Data: []byte("<h1>home</h1>"), ModTime: modTime},
+		"app.3f2a9c1b.js":    {Data: []byte("console.log(1)"), ModTime: modTime},
+		"app.3f2a9c1b.js.gz": {Data: []byte("gzipped"), ModTime: modTime},
+		"docs/readme.txt":    {Data: []byte("readme")},
+		"empty/.keep":        {Data: []byte{}},
+	}
+	m.Static("/static", fsys, mig.StaticOptions{Precompressed: true})
+	m.GET("/static/api", func(c *mig.Context) error { return c.Raw([]byte("api")) })
+
+	do := func(target string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		rec := httptest.NewRecorder()
+		m.Mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("Index file", func(t *testing.T) {
+		rec := do("/static/")
+		assertEqual(t, http.StatusOK, rec.Code, "Status")
+		assertEqual(t, "<h1>home</h1>", rec.Body.String(), "Body")
+		assertEqual(t, "no-cache", rec.Header().Get("Cache-Control"), "Cache-Control")
+		assertEqual(t, modTime.Format(http.TimeFormat), rec.Header().Get("Last-Modified"), "Last-Modified")
+	})
+
+	t.Run("Conditional request", func(t *testing.T) {
+		etag := do("/static/index.html").Header().Get("ETag")
+		if etag == "" {
+			t.Fatal("missing ETag")
+		}
+		assertEqual(t, http.StatusNotModified, do("/static/index.html", "If-None-Match", etag).Code, "If-None-Match")
+	})
+
+	t.Run("Fingerprinted file", func(t *testing.T) {
+		rec := do("/static/app.3f2a9c1b.js")
+		assertEqual(t, "console.log(1)", rec.Body.String(), "Body")
+		assertEqual(t, "public, max-age=31536000, immutable", rec.Header().Get("Cache-Control"), "Cache-Control")
+		assertEqual(t, "Accept-Encoding", rec.Header().Get("Vary"), "Vary")
+	})
+
+	t.Run("Precompressed sibling", func(t *testing.T) {
+		rec := do("/static/app.3f2a9c1b.js", "Accept-Encoding", "br, gzip")
+		assertEqual(t, "gzipped", rec.Body.String(), "Body")
+		assertEqual(t, "gzip", rec.Header().Get("Content-Encoding"), "Content-Encoding")
+		assertEqual(t, "text/javascript; charset=utf-8", rec.Header().Get("Content-Type"), "Content-Type")
+
+		rec = do("/static/app.3f2a9c1b.js", "Accept-Encoding", "gzip;q=0")
+		assertEqual(t, "console.log(1)", rec.Body.String(), "gzip refused")
+	})
+
+	t.Run("Content hash ETag without modtime", func(t *testing.T) {
+		rec := do("/static/docs/readme.txt")
+		assertEqual(t, "readme", rec.Body.String(), "Body")
+		assertEqual(t, 34, len(rec.Header().Get("ETag")), "Hash ETag")
+	})
+
+	t.Run("Directory redirect", func(t *testing.T) {
+		rec := do("/static/docs")
+		assertEqual(t, http.StatusMovedPermanently, rec.Code, "Status")
+		assertEqual(t, "/static/docs/", rec.Header().Get("Location"), "Location")
+	})
+
+	t.Run("No directory listing", func(t *testing.T) {
+		assertEqual(t, http.StatusNotFound, do("/static/empty/").Code, "Status")
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		rec := do("/static/missing.css", "Accept", "application/json")
+		assertEqual(t, http.StatusNotFound, rec.Code, "Status")
+		assertEqual(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"), "Handled by ErrorHandler")
+	})
+
+	t.Run("Routes registered on the same prefix", func(t *testing.T) {
+		assertEqual(t, "api", do("/static/api").Body.String(), "Body")
+	})
+}
+
+func TestStatic_Browse(t *testing.T) {
+	m := mig.New(context.Background())
+	restoreLogger := setupSilentLogger(m)
+	defer restoreLogger()
+
+	m.Static("/files", fstest.MapFS{
+		"a <b>.txt": {Data: []byte("a")},
+		"sub/c.txt": {Data: []byte("c")},
+	}, mig.StaticOptions{Browse: true})
+
+	rec := httptest.NewRecorder()
+	m.Mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/files/", nil))
+	assertEqual(t, http.StatusOK, rec.Code, "Status")
+	body := rec.Body.String()
+	for _, want := range []string{`<a href="a%20%3Cb%3E.txt">a &lt;b&gt;.txt</a>`, `<a href="sub/">sub/</a>`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("listing does not contain %s:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Package systemd integrates mig with systemd socket activation and the
+// sd_notify protocol.
+//
+//	m := mig.New(ctx)
+//	if _, err := systemd.AddListeners(m); err != nil {
+//		log.Fatal(err)
+//	}
+//	systemd.EnableNotify(m)
+//	m.Run("") // serve only the sockets passed by systemd
+//
+// Everything is driven by environment variables (LISTEN_FDS, LISTEN_PID,
+// LISTEN_FDNAMES, NOTIFY_SOCKET, WATCHDOG_USEC, WATCHDOG_PID), so it can be
+// exercised locally by setting them by hand.
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/levmv/mig"
+)
+
+// listenFDsStart is the first inherited file descriptor, SD_LISTEN_FDS_START.
+var listenFDsStart = 3
+
+// Listener is an inherited listener together with its name from
+// LISTEN_FDNAMES ("unknown" if systemd did not provide names).
+type Listener struct {
+	net.Listener
+	Name string
+}
+
+// Listeners returns the listeners passed by socket activation, in file
+// descriptor order. It returns nil if the process was not socket activated.
+// The LISTEN_* variables are unset so that child processes do not inherit them.
+func Listeners() ([]Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, nil
+	}
+	var names []string
+	if v := os.Getenv("LISTEN_FDNAMES"); v != "" {
+		names = strings.Split(v, ":")
+	}
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	listeners := make([]Listener, 0, n)
+	for i := range n {
+		name := "unknown"
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		f := os.NewFile(uintptr(listenFDsStart+i), name)
+		ln, err := net.FileListener(f)
+		f.Close() // FileListener works on a duplicate
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, fmt.Errorf("systemd: fd %d (%s): %w", listenFDsStart+i, name, err)
+		}
+		listeners = append(listeners, Listener{Listener: ln, Name: name})
+	}
+	return listeners, nil
+}
+
+// AddListeners adds the socket-activated listeners to m. If names are given,
+// only listeners with those names (from the FileDescriptorName= setting) are
+// served and the rest are closed. It returns the number of listeners added.
+func AddListeners(m *mig.Mig, names ...string) (int, error) {
+	listeners, err := Listeners()
+	if err != nil {
+		return 0, err
+	}
+	added := 0
+	for _, ln := range listeners {
+		if len(names) > 0 && !slices.Contains(names, ln.Name) {
+			ln.Close()
+			continue
+		}
+		m.AddListener(ln.Listener)
+		added++
+	}
+	return added, nil
+}
+
+// Notify sends a state string such as "READY=1" to the service manager.
+// It does nothing if NOTIFY_SOCKET is not set.
+func Notify(state string) error {
+	sock := os.Getenv("NOTIFY_SOCKET")
+	if sock == "" {
+		return nil
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: sock, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// WatchdogInterval returns how often WATCHDOG=1 should be sent: half of
+// WATCHDOG_USEC. The second result is false if the watchdog is not enabled
+// for this process.
+func WatchdogInterval() (time.Duration, bool) {
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, false
+	}
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0, false
+	}
+	return time.Duration(usec) * time.Microsecond / 2, true
+}
+
+// EnableNotify registers lifecycle hooks on m that send READY=1 once the
+// server listens, WATCHDOG=1 periodically while it runs (if the watchdog is
+// enabled), MAINPID of the new process after mig.Mig.Restart, and
+// STOPPING=1 when shutdown begins.
+//
+// Use it with Type=notify. With GracefulRestart, also set NotifyAccess=all:
+// the new process sends READY=1 before systemd knows it as the main process.
+func EnableNotify(m *mig.Mig) {
+	stopWatchdog := func() {}
+
+	m.OnListen(func(context.Context) error {
+		if interval, ok := WatchdogInterval(); ok {
+			// A process started by Restart takes over the watchdog once it
+			// becomes the main process.
+			os.Unsetenv("WATCHDOG_PID")
+			ctx, cancel := context.WithCancel(context.Background())
+			stopWatchdog = cancel
+			go func() {
+				t := time.NewTicker(interval)
+				defer t.Stop()
+				for {
+					select {
+					case <-t.C:
+						if err := Notify("WATCHDOG=1"); err != nil {
+							m.Logger.Error("systemd watchdog notification failed", "err", err)
+						}
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+		}
+		return Notify("READY=1")
+	})
+	var handedOver atomic.Bool
+	m.OnRestart(func(_ context.Context, pid int) error {
+		if err := Notify("MAINPID=" + strconv.Itoa(pid)); err != nil {
+			return err
+		}
+		handedOver.Store(true)
+		return nil
+	})
+	m.OnShutdown(func(context.Context) error {
+		if handedOver.Load() {
+			return nil // the service keeps running in the new process
+		}
+		return Notify("STOPPING=1")
+	})
+	m.OnStopped(func(context.Context) error {
+		stopWatchdog()
+		return nil
+	})
+}
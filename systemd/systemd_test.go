@@ -0,0 +1,159 @@
+//go:build linux
+
+package systemd
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/levmv/mig"
+)
+
+func assertEqual(t *testing.T, expected, actual any, msg string) {
+	t.Helper()
+	if expected != actual {
+		t.Fatalf("%s: mismatch\n\texp: %v\n\tgot: %v", msg, expected, actual)
+	}
+}
+
+// fakeActivation passes listeners to the process the way systemd would:
+// consecutive file descriptors starting at listenFDsStart plus LISTEN_* vars.
+func fakeActivation(t *testing.T, names string, lns ...*net.TCPListener) {
+	t.Helper()
+	var files []*os.File
+	for _, ln := range lns {
+		f, err := ln.File()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, f)
+		ln.Close()
+	}
+	// Make the descriptors consecutive by dup'ing them above the current ones.
+	start := int(files[0].Fd()) + 100
+	for i, f := range files {
+		if err := syscall.Dup3(int(f.Fd()), start+i, 0); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+	old := listenFDsStart
+	listenFDsStart = start
+	t.Cleanup(func() { listenFDsStart = old })
+
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	t.Setenv("LISTEN_FDS", strconv.Itoa(len(lns)))
+	t.Setenv("LISTEN_FDNAMES", names)
+}
+
+func tcpListener(t *testing.T) *net.TCPListener {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ln.(*net.TCPListener)
+}
+
+func TestListeners_NotActivated(t *testing.T) {
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()+1))
+	t.Setenv("LISTEN_FDS", "1")
+	lns, err := Listeners()
+	if err != nil || lns != nil {
+		t.Fatalf("expected no listeners for another pid, got %v, %v", lns, err)
+	}
+}
+
+func TestAddListeners(t *testing.T) {
+	fakeActivation(t, "http:admin", tcpListener(t), tcpListener(t))
+
+	m := mig.New(context.Background())
+	m.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	m.GET("/", func(c *mig.Context) error {
+		return c.Raw([]byte("OK"))
+	})
+
+	n, err := AddListeners(m, "http")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, 1, n, "Listeners added by name")
+	assertEqual(t, "", os.Getenv("LISTEN_FDS"), "LISTEN_FDS should be unset")
+
+	if err := m.Start(""); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Shutdown()
+
+	res, err := http.Get("http://" + m.Addr().String() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	assertEqual(t, "OK", string(body), "Response over inherited socket")
+}
+
+func TestEnableNotify(t *testing.T) {
+	sockPath := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: sockPath, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	t.Setenv("NOTIFY_SOCKET", sockPath)
+	t.Setenv("WATCHDOG_USEC", "40000")
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+
+	read := func() string {
+		t.Helper()
+		buf := make([]byte, 256)
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(buf[:n])
+	}
+
+	m := mig.New(context.Background())
+	m.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	EnableNotify(m)
+
+	if err := m.Start("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, "READY=1", read(), "Notification after start")
+	assertEqual(t, "WATCHDOG=1", read(), "Watchdog keep-alive")
+
+	if err := m.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if msg := read(); msg != "WATCHDOG=1" {
+			assertEqual(t, "STOPPING=1", msg, "Notification on shutdown")
+			break
+		}
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	t.Setenv("WATCHDOG_USEC", "2000000")
+	t.Setenv("WATCHDOG_PID", "")
+	d, ok := WatchdogInterval()
+	assertEqual(t, true, ok, "Watchdog enabled")
+	assertEqual(t, time.Second, d, "Half of WATCHDOG_USEC")
+
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()+1))
+	_, ok = WatchdogInterval()
+	assertEqual(t, false, ok, "Watchdog for another process")
+}
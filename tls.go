@@ -0,0 +1,118 @@
+package mig
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// certCheckInterval limits how often certificate files are checked for changes.
+var certCheckInterval = time.Second
+
+// StartTLS is like Start, but serves HTTPS on all listeners.
+//
+// If certFile and keyFile are set, the certificate is loaded from them and
+// reloaded automatically when the files change on disk, so rotated
+// certificates are picked up without a restart. Otherwise the certificates
+// must be set in TLSConfig.
+//
+// TLSConfig is used as the base configuration. For client certificate
+// verification (mTLS) set its ClientCAs and ClientAuth; the verified
+// certificate is available from Context.ClientCertificate. TLS 1.2 is the
+// minimum version unless TLSConfig says otherwise; Go's default cipher suites
+// and curves are already restricted to modern ones.
+func (m *Mig) StartTLS(addr, certFile, keyFile string) error {
+	cfg, err := m.tlsConfig(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	return m.start(addr, cfg)
+}
+
+// RunTLS is like Run, but serves HTTPS. See StartTLS.
+func (m *Mig) RunTLS(addr, certFile, keyFile string) error {
+	return m.run(func() error { return m.StartTLS(addr, certFile, keyFile) })
+}
+
+func (m *Mig) tlsConfig(certFile, keyFile string) (*tls.Config, error) {
+	cfg := &tls.Config{}
+	if m.TLSConfig != nil {
+		cfg = m.TLSConfig.Clone()
+	}
+	if cfg.MinVersion == 0 {
+		cfg.MinVersion = tls.VersionTLS12
+	}
+	if certFile != "" || keyFile != "" {
+		r := &certReloader{certFile: certFile, keyFile: keyFile, logger: m.Logger}
+		if err := r.reload(); err != nil {
+			return nil, err
+		}
+		cfg.GetCertificate = r.getCertificate
+	}
+	if len(cfg.Certificates) == 0 && cfg.GetCertificate == nil && cfg.GetConfigForClient == nil {
+		return nil, errors.New("mig: StartTLS requires certificate files or certificates in TLSConfig")
+	}
+	return cfg, nil
+}
+
+// certReloader serves a certificate from files, reloading it when their
+// modification time changes.
+type certReloader struct {
+	certFile, keyFile string
+	logger            *slog.Logger
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+	checked time.Time
+}
+
+func (r *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Since(r.checked) >= certCheckInterval {
+		r.checked = time.Now()
+		if err := r.reloadLocked(); err != nil {
+			// Keep serving the old certificate; the files may be mid-rotation.
+			r.logger.Error("Failed to reload TLS certificate", "err", err)
+		}
+	}
+	return r.cert, nil
+}
+
+func (r *certReloader) reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checked = time.Now()
+	return r.reloadLocked()
+}
+
+func (r *certReloader) reloadLocked() error {
+	var modTime time.Time
+	for _, name := range []string{r.certFile, r.keyFile} {
+		fi, err := os.Stat(name)
+		if err != nil {
+			return err
+		}
+		if fi.ModTime().After(modTime) {
+			modTime = fi.ModTime()
+		}
+	}
+	if r.cert != nil && modTime.Equal(r.modTime) {
+		return nil
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("mig: load TLS certificate: %w", err)
+	}
+	if r.cert != nil {
+		r.logger.Info("TLS certificate reloaded", "file", r.certFile)
+	}
+	r.cert = &cert
+	r.modTime = modTime
+	return nil
+}
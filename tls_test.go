@@ -0,0 +1,142 @@
+package mig_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/levmv/mig"
+)
+
+// selfSigned creates a self-signed certificate usable both as a server
+// certificate for 127.0.0.1 and as a client certificate.
+func selfSigned(t *testing.T, cn string, serial int64) (*x509.Certificate, tls.Certificate, []byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assertNoError(t, err, "GenerateKey failed")
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assertNoError(t, err, "CreateCertificate failed")
+	cert, err := x509.ParseCertificate(der)
+	assertNoError(t, err, "ParseCertificate failed")
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assertNoError(t, err, "MarshalECPrivateKey failed")
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	assertNoError(t, err, "X509KeyPair failed")
+	return cert, pair, certPEM, keyPEM
+}
+
+func TestStartTLS_ReloadsCertificate(t *testing.T) {
+	m := mig.New(context.Background())
+	restoreLogger := setupSilentLogger(m)
+	defer restoreLogger()
+	m.GET("/", func(c *mig.Context) error { return c.Raw([]byte("OK")) })
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writePair := func(certPEM, keyPEM []byte) {
+		assertNoError(t, os.WriteFile(certFile, certPEM, 0o600), "Write cert failed")
+		assertNoError(t, os.WriteFile(keyFile, keyPEM, 0o600), "Write key failed")
+	}
+
+	cert1, _, certPEM, keyPEM := selfSigned(t, "server", 1)
+	writePair(certPEM, keyPEM)
+	assertNoError(t, m.StartTLS("127.0.0.1:0", certFile, keyFile), "StartTLS failed")
+	defer m.Shutdown()
+	url := "https://" + m.Addr().String() + "/"
+
+	serverSerial := func(root *x509.Certificate) int64 {
+		t.Helper()
+		pool := x509.NewCertPool()
+		pool.AddCert(root)
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{RootCAs: pool},
+			DisableKeepAlives: true,
+		}}
+		res, err := client.Get(url)
+		assertNoError(t, err, "HTTPS request failed")
+		res.Body.Close()
+		assertEqual(t, uint16(tls.VersionTLS13), res.TLS.Version, "TLS version")
+		return res.TLS.PeerCertificates[0].SerialNumber.Int64()
+	}
+	assertEqual(t, int64(1), serverSerial(cert1), "Initial certificate")
+
+	cert2, _, certPEM, keyPEM := selfSigned(t, "server", 2)
+	writePair(certPEM, keyPEM)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, future, future)
+	time.Sleep(1100 * time.Millisecond) // certificate files are checked at most once a second
+	assertEqual(t, int64(2), serverSerial(cert2), "Rotated certificate")
+}
+
+func TestStartTLS_ClientCertificate(t *testing.T) {
+	m := mig.New(context.Background())
+	restoreLogger := setupSilentLogger(m)
+	defer restoreLogger()
+	m.GET("/", func(c *mig.Context) error {
+		cert := c.ClientCertificate()
+		if cert == nil {
+			return c.Raw([]byte("anonymous"))
+		}
+		return c.Raw([]byte(cert.Subject.CommonName))
+	})
+
+	serverCert, serverPair, _, _ := selfSigned(t, "server", 1)
+	clientCert, clientPair, _, _ := selfSigned(t, "client-42", 2)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	m.TLSConfig = &tls.Config{
+		Certificates: []tls.Certificate{serverPair},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+	}
+	assertNoError(t, m.StartTLS("127.0.0.1:0", "", ""), "StartTLS failed")
+	defer m.Shutdown()
+	url := "https://" + m.Addr().String() + "/"
+
+	roots := x509.NewCertPool()
+	roots.AddCert(serverCert)
+	anonymous := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	withCert := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{clientPair},
+	}}}
+
+	assertEqual(t, "anonymous", get(t, anonymous, url), "Without client certificate")
+	assertEqual(t, "client-42", get(t, withCert, url), "Verified client identity")
+}
+
+func TestStartTLS_RequiresCertificate(t *testing.T) {
+	m := mig.New(context.Background())
+	restoreLogger := setupSilentLogger(m)
+	defer restoreLogger()
+
+	if err := m.StartTLS("127.0.0.1:0", "", ""); err == nil {
+		t.Fatal("expected an error without certificates")
+	}
+}
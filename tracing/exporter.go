@@ -0,0 +1,78 @@
+package tracing
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"slices"
+	"sync"
+)
+
+// InMemoryExporter keeps finished spans in memory. It is meant for tests
+// and local debugging.
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+func (e *InMemoryExporter) ExportSpan(s *Span) error {
+	e.mu.Lock()
+	e.spans = append(e.spans, s)
+	e.mu.Unlock()
+	return nil
+}
+
+// Spans returns the spans exported so far, in the order they finished.
+func (e *InMemoryExporter) Spans() []*Span {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return slices.Clone(e.spans)
+}
+
+// Reset drops all recorded spans.
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	e.spans = nil
+	e.mu.Unlock()
+}
+
+// JSONLinesExporter writes each finished span as one JSON object per line.
+type JSONLinesExporter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc *json.Encoder
+}
+
+func NewJSONLinesExporter(w io.Writer) *JSONLinesExporter {
+	return &JSONLinesExporter{w: w, enc: json.NewEncoder(w)}
+}
+
+// OpenJSONLinesFile creates an exporter appending to the file at path.
+// Close the exporter to close the file.
+func OpenJSONLinesFile(path string) (*JSONLinesExporter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return NewJSONLinesExporter(f), nil
+}
+
+func (e *JSONLinesExporter) ExportSpan(s *Span) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.enc.Encode(s)
+}
+
+// Close closes the underlying writer if it is an io.Closer.
+func (e *JSONLinesExporter) Close() error {
+	if c, ok := e.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
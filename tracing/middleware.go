@@ -0,0 +1,76 @@
+package tracing
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/levmv/mig"
+)
+
+// Middleware returns a middleware that records a server span for every request.
+//
+// The trace is continued from the incoming traceparent and tracestate
+// headers if they are valid, and the new span's context is sent back in the
+// same headers. The span is stored in the request context (see
+// SpanFromContext), its IDs are added to c.Logger as trace_id and span_id,
+// and, unless a request ID is already set, the trace ID becomes the request ID.
+// Register it before middleware.RequestID and middleware.RequestLogger.
+func Middleware(tracer *Tracer) mig.MiddlewareFunc {
+	return func(next mig.Handler) mig.Handler {
+		return func(c *mig.Context) (err error) {
+			var parent SpanContext
+			if p, perr := ParseTraceparent(c.Request.Header.Get(TraceparentHeader)); perr == nil {
+				parent = p
+				parent.TraceState = c.Request.Header.Get(TracestateHeader)
+			}
+
+			name := c.Request.Method
+			route := c.Route()
+			if route != nil {
+				name += " " + route.Pattern
+			}
+			span := tracer.newSpan(name, KindServer, parent)
+			span.SetAttribute("http.request.method", c.Request.Method)
+			span.SetAttribute("url.path", c.Request.URL.Path)
+			if route != nil {
+				span.SetAttribute("http.route", route.Pattern)
+			}
+
+			c.Request = c.Request.WithContext(ContextWithSpan(c.Request.Context(), span))
+			sc := span.Context()
+			c.Response.Header().Set(TraceparentHeader, sc.Traceparent())
+			if sc.TraceState != "" {
+				c.Response.Header().Set(TracestateHeader, sc.TraceState)
+			}
+			c.Logger = c.Logger.With(
+				slog.String("trace_id", sc.TraceID.String()),
+				slog.String("span_id", sc.SpanID.String()),
+			)
+			if c.RequestID() == "" {
+				c.SetRequestID(sc.TraceID.String())
+			}
+
+			panicked := true
+			defer func() {
+				status := http.StatusInternalServerError
+				if !panicked {
+					status = c.ResponseStatus(err)
+				}
+				span.SetAttribute("http.response.status_code", status)
+				if status >= 500 {
+					msg := strconv.Itoa(status)
+					if err != nil {
+						msg = err.Error()
+					}
+					span.SetStatus(StatusError, msg)
+				}
+				span.Finish()
+			}()
+
+			err = next(c)
+			panicked = false
+			return err
+		}
+	}
+}
@@ -0,0 +1,257 @@
+// Package tracing implements W3C Trace Context propagation and simple span
+// recording for mig applications.
+//
+// The middleware continues a trace from the incoming traceparent header (or
+// starts a new one), records a server span per request and hands finished
+// spans to an Exporter:
+//
+//	tracer := tracing.NewTracer(tracing.NewJSONLinesExporter(os.Stderr))
+//	m.Use(tracing.Middleware(tracer), middleware.RequestID(), middleware.RequestLogger())
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Header names defined by W3C Trace Context.
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+)
+
+// TraceID identifies a trace. It is encoded as 32 lowercase hex characters.
+type TraceID [16]byte
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+
+func (id TraceID) IsValid() bool { return id != TraceID{} }
+
+func (id TraceID) MarshalText() ([]byte, error) { return []byte(id.String()), nil }
+
+// SpanID identifies a span within a trace. It is encoded as 16 lowercase hex characters.
+type SpanID [8]byte
+
+func (id SpanID) String() string { return hex.EncodeToString(id[:]) }
+
+func (id SpanID) IsValid() bool { return id != SpanID{} }
+
+func (id SpanID) MarshalText() ([]byte, error) {
+	if !id.IsValid() {
+		return nil, nil
+	}
+	return []byte(id.String()), nil
+}
+
+// FlagSampled is the trace-flags bit telling that the caller records the trace.
+const FlagSampled byte = 0x01
+
+// SpanContext is the part of a span that is propagated across process boundaries.
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Flags      byte
+	TraceState string
+}
+
+func (sc SpanContext) IsValid() bool { return sc.TraceID.IsValid() && sc.SpanID.IsValid() }
+
+func (sc SpanContext) Sampled() bool { return sc.Flags&FlagSampled != 0 }
+
+// Traceparent formats sc as a version 00 traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + hex.EncodeToString([]byte{sc.Flags})
+}
+
+var errInvalidTraceparent = errors.New("tracing: invalid traceparent")
+
+// ParseTraceparent parses a traceparent header value. Versions other than 00
+// are accepted as long as their first four fields have the version 00 layout.
+func ParseTraceparent(s string) (SpanContext, error) {
+	var sc SpanContext
+	// version "-" trace-id "-" parent-id "-" trace-flags
+	if len(s) < 55 || s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return sc, errInvalidTraceparent
+	}
+	version, err := hex.DecodeString(s[:2])
+	if err != nil || version[0] == 0xff || (version[0] == 0 && len(s) != 55) ||
+		(len(s) > 55 && s[55] != '-') || !isLowerHex(s[:55]) {
+		return sc, errInvalidTraceparent
+	}
+	var flags [1]byte
+	if _, err := hex.Decode(sc.TraceID[:], []byte(s[3:35])); err != nil {
+		return sc, errInvalidTraceparent
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(s[36:52])); err != nil {
+		return sc, errInvalidTraceparent
+	}
+	if _, err := hex.Decode(flags[:], []byte(s[53:55])); err != nil {
+		return sc, errInvalidTraceparent
+	}
+	sc.Flags = flags[0]
+	if !sc.IsValid() {
+		return sc, errInvalidTraceparent
+	}
+	return sc, nil
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '-' && (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// Span kinds.
+const (
+	KindServer   = "server"
+	KindInternal = "internal"
+)
+
+// Span statuses.
+const (
+	StatusUnset = ""
+	StatusOK    = "ok"
+	StatusError = "error"
+)
+
+// Span is a timed operation within a trace.
+type Span struct {
+	Name          string         `json:"name"`
+	Kind          string         `json:"kind"`
+	TraceID       TraceID        `json:"trace_id"`
+	SpanID        SpanID         `json:"span_id"`
+	ParentSpanID  SpanID         `json:"parent_span_id"`
+	TraceState    string         `json:"trace_state,omitempty"`
+	Start         time.Time      `json:"start"`
+	End           time.Time      `json:"end"`
+	Status        string         `json:"status,omitempty"`
+	StatusMessage string         `json:"status_message,omitempty"`
+	Attributes    map[string]any `json:"attributes,omitempty"`
+
+	flags  byte
+	tracer *Tracer
+	mu     sync.Mutex
+	ended  bool
+}
+
+// Context returns the span's propagation context.
+func (s *Span) Context() SpanContext {
+	return SpanContext{TraceID: s.TraceID, SpanID: s.SpanID, Flags: s.flags, TraceState: s.TraceState}
+}
+
+// SetAttribute records a key/value attribute on the span.
+func (s *Span) SetAttribute(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Attributes == nil {
+		s.Attributes = make(map[string]any)
+	}
+	s.Attributes[key] = value
+}
+
+// SetStatus sets the span status to StatusOK or StatusError.
+func (s *Span) SetStatus(status, message string) {
+	s.mu.Lock()
+	s.Status, s.StatusMessage = status, message
+	s.mu.Unlock()
+}
+
+// Finish ends the span and exports it if it is sampled. Further calls are ignored.
+func (s *Span) Finish() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.End = time.Now()
+	s.mu.Unlock()
+
+	if s.flags&FlagSampled != 0 && s.tracer.Exporter != nil {
+		if err := s.tracer.Exporter.ExportSpan(s); err != nil && s.tracer.OnError != nil {
+			s.tracer.OnError(err)
+		}
+	}
+}
+
+// Exporter receives finished, sampled spans.
+type Exporter interface {
+	ExportSpan(*Span) error
+}
+
+// Tracer creates spans and sends them to its Exporter when they finish.
+type Tracer struct {
+	Exporter Exporter
+	// OnError is called when the exporter fails. Errors are dropped if nil.
+	OnError func(error)
+}
+
+func NewTracer(exporter Exporter) *Tracer {
+	return &Tracer{Exporter: exporter}
+}
+
+type spanKey struct{}
+
+// SpanFromContext returns the current span, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// ContextWithSpan returns a copy of ctx carrying s.
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, s)
+}
+
+// Start begins a span as a child of the span in ctx, or as the root of a new
+// trace if there is none. Call Finish on the returned span when done.
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, *Span) {
+	var parent SpanContext
+	if p := SpanFromContext(ctx); p != nil {
+		parent = p.Context()
+	}
+	s := t.newSpan(name, KindInternal, parent)
+	return ContextWithSpan(ctx, s), s
+}
+
+func (t *Tracer) newSpan(name, kind string, parent SpanContext) *Span {
+	s := &Span{
+		Name:   name,
+		Kind:   kind,
+		Start:  time.Now(),
+		tracer: t,
+	}
+	if parent.IsValid() {
+		s.TraceID = parent.TraceID
+		s.ParentSpanID = parent.SpanID
+		s.TraceState = parent.TraceState
+		s.flags = parent.Flags
+	} else {
+		rand.Read(s.TraceID[:])
+		s.flags = FlagSampled
+	}
+	rand.Read(s.SpanID[:])
+	return s
+}
+
+// Inject writes the trace context of the span in ctx into h, for outgoing requests.
+func Inject(ctx context.Context, h http.Header) {
+	s := SpanFromContext(ctx)
+	if s == nil {
+		return
+	}
+	sc := s.Context()
+	h.Set(TraceparentHeader, sc.Traceparent())
+	if sc.TraceState != "" {
+		h.Set(TracestateHeader, sc.TraceState)
+	}
+}
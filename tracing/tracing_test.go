@@ -0,0 +1,117 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/levmv/mig"
+	"github.com/levmv/mig/middleware"
+)
+
+func assertEqual(t *testing.T, expected, actual any, msg string) {
+	t.Helper()
+	if expected != actual {
+		t.Fatalf("%s: mismatch\n\texp: %v\n\tgot: %v", msg, expected, actual)
+	}
+}
+
+func TestParseTraceparent(t *testing.T) {
+	testCases := []struct {
+		in    string
+		valid bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"garbage", false},
+		{"", false},
+	}
+	for _, tc := range testCases {
+		sc, err := ParseTraceparent(tc.in)
+		assertEqual(t, tc.valid, err == nil, "validity of "+tc.in)
+		if tc.valid {
+			assertEqual(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String(), "trace id")
+			assertEqual(t, "00f067aa0ba902b7", sc.SpanID.String(), "span id")
+			assertEqual(t, true, sc.Sampled(), "sampled flag")
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var logs bytes.Buffer
+	m := mig.New(context.Background())
+	m.Logger = slog.New(slog.NewTextHandler(&logs, nil))
+
+	exp := NewInMemoryExporter()
+	tracer := NewTracer(exp)
+	m.Use(Middleware(tracer), middleware.RequestID())
+
+	var requestID string
+	m.GET("/users/{id}", func(c *mig.Context) error {
+		requestID = c.RequestID()
+		_, child := tracer.Start(c.Request.Context(), "db.query")
+		child.Finish()
+		c.Logger.Info("handled")
+		return errors.New("boom")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/7", nil)
+	req.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set(TracestateHeader, "vendor=abc")
+	rec := httptest.NewRecorder()
+	m.Mux.ServeHTTP(rec, req)
+
+	spans := exp.Spans()
+	assertEqual(t, 2, len(spans), "Exported span count")
+	child, server := spans[0], spans[1]
+
+	assertEqual(t, "GET /users/{id}", server.Name, "Server span name")
+	assertEqual(t, KindServer, server.Kind, "Server span kind")
+	assertEqual(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.TraceID.String(), "Trace continued")
+	assertEqual(t, "00f067aa0ba902b7", server.ParentSpanID.String(), "Remote parent")
+	assertEqual(t, "vendor=abc", server.TraceState, "Trace state propagated")
+	assertEqual(t, "/users/{id}", server.Attributes["http.route"], "Route attribute")
+	assertEqual(t, 500, server.Attributes["http.response.status_code"], "Status attribute")
+	assertEqual(t, StatusError, server.Status, "Span status")
+
+	assertEqual(t, server.TraceID, child.TraceID, "Child trace id")
+	assertEqual(t, server.SpanID, child.ParentSpanID, "Child parent id")
+
+	assertEqual(t, server.Context().Traceparent(), rec.Header().Get(TraceparentHeader), "Response traceparent")
+	assertEqual(t, "4bf92f3577b34da6a3ce929d0e0e4736", requestID, "Trace ID used as request ID")
+	if !strings.Contains(logs.String(), "trace_id=4bf92f3577b34da6a3ce929d0e0e4736") {
+		t.Errorf("logger should carry trace_id, got: %s", logs.String())
+	}
+}
+
+func TestJSONLinesExporter(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := NewTracer(NewJSONLinesExporter(&buf))
+
+	_, span := tracer.Start(context.Background(), "job")
+	span.SetAttribute("count", 3)
+	span.Finish()
+	span.Finish() // exported only once
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assertEqual(t, 1, len(lines), "Line count")
+
+	var got map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &got); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, "job", got["name"], "Span name")
+	assertEqual(t, span.TraceID.String(), got["trace_id"], "Trace id")
+	assertEqual(t, "", got["parent_span_id"], "Root span has no parent")
+}
@@ -0,0 +1,242 @@
+package mig
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// UploadOptions limits multipart uploads. Mig.Upload holds the options used
+// by FormFile and FormFiles.
+type UploadOptions struct {
+	// MaxFileSize limits each file. Default is 32 MB.
+	MaxFileSize int64
+	// MaxTotalSize limits the whole request body. Default is 64 MB.
+	MaxTotalSize int64
+	// AllowedTypes lists the content types files may have, e.g. "image/png"
+	// or "image/*". The type is sniffed from the content with
+	// http.DetectContentType, not taken from the client. Empty allows all.
+	AllowedTypes []string
+	// TempDir is where FormFile and FormFiles store files. Default is
+	// os.TempDir().
+	TempDir string
+}
+
+// UploadPart is a file being read from a multipart request by ReadMultipart.
+// Reading past MaxFileSize fails with a 413 error.
+type UploadPart struct {
+	io.Reader
+	Field    string
+	Filename string
+	// ContentType is sniffed from the first 512 bytes of the content.
+	ContentType string
+}
+
+// UploadedFile is a file stored by FormFile or FormFiles. The temporary file
+// is removed when the request is finished; move it to keep it.
+type UploadedFile struct {
+	Field       string
+	Filename    string
+	ContentType string
+	Size        int64
+	Path        string
+}
+
+// Open opens the stored file for reading.
+func (f *UploadedFile) Open() (*os.File, error) {
+	return os.Open(f.Path)
+}
+
+// FormFile returns the first file uploaded in field name of a
+// multipart/form-data request, stored in a temporary file according to
+// Mig.Upload. Other form fields are available through Request.FormValue.
+func (c *Context) FormFile(name string) (*UploadedFile, error) {
+	files, err := c.FormFiles(name)
+	if err != nil {
+		return nil, err
+	}
+	return files[0], nil
+}
+
+// FormFiles returns all files uploaded in field name. See FormFile.
+func (c *Context) FormFiles(name string) ([]*UploadedFile, error) {
+	if c.uploads == nil {
+		uploads := map[string][]*UploadedFile{}
+		opts := c.Mig.Upload
+		err := c.ReadMultipart(opts, func(p *UploadPart) error {
+			f, err := os.CreateTemp(opts.TempDir, "mig-upload-")
+			if err != nil {
+				return err
+			}
+			c.OnCleanup(func() { os.Remove(f.Name()) })
+			n, err := io.Copy(f, p)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return err
+			}
+			uploads[p.Field] = append(uploads[p.Field], &UploadedFile{
+				Field:       p.Field,
+				Filename:    p.Filename,
+				ContentType: p.ContentType,
+				Size:        n,
+				Path:        f.Name(),
+			})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		c.uploads = uploads
+	}
+	files := c.uploads[name]
+	if len(files) == 0 {
+		e := NewHTTPError(http.StatusBadRequest)
+		e.Internal = http.ErrMissingFile
+		return nil, e
+	}
+	return files, nil
+}
+
+// ReadMultipart streams a multipart/form-data request body, calling handle
+// for each file part in order, without buffering files in memory or on disk.
+// Other form fields are collected and made available through
+// Request.FormValue and Request.PostForm.
+//
+// Bodies over MaxTotalSize and files over MaxFileSize fail with 413, files
+// of types not in AllowedTypes with 415.
+func (c *Context) ReadMultipart(opts UploadOptions, handle func(p *UploadPart) error) error {
+	if opts.MaxFileSize <= 0 {
+		opts.MaxFileSize = 32 << 20
+	}
+	if opts.MaxTotalSize <= 0 {
+		opts.MaxTotalSize = 64 << 20
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Response, c.Request.Body, opts.MaxTotalSize)
+	mr, err := c.Request.MultipartReader()
+	if err != nil {
+		e := NewHTTPError(http.StatusUnsupportedMediaType)
+		if !errors.Is(err, http.ErrNotMultipart) {
+			e = NewHTTPError(http.StatusBadRequest)
+		}
+		e.Internal = err
+		return e
+	}
+
+	form := url.Values{}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return uploadError(err, http.StatusBadRequest)
+		}
+
+		if part.FileName() == "" {
+			// Regular fields are small; the total limit bounds them.
+			v, err := io.ReadAll(part)
+			if err != nil {
+				return uploadError(err, http.StatusBadRequest)
+			}
+			form.Add(part.FormName(), string(v))
+			continue
+		}
+
+		br := bufio.NewReaderSize(&limitedPart{r: part, n: opts.MaxFileSize}, 512)
+		head, err := br.Peek(512)
+		if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+			return uploadError(err, http.StatusBadRequest)
+		}
+		ctype := http.DetectContentType(head)
+		if !typeAllowed(ctype, opts.AllowedTypes) {
+			e := NewHTTPError(http.StatusUnsupportedMediaType)
+			e.Internal = errors.New("mig: upload of type " + ctype + " is not allowed")
+			return e
+		}
+		err = handle(&UploadPart{
+			Reader:      br,
+			Field:       part.FormName(),
+			Filename:    part.FileName(),
+			ContentType: ctype,
+		})
+		if err != nil {
+			return uploadError(err, 0)
+		}
+	}
+
+	// Like ParseMultipartForm, Form holds the query values followed by the
+	// posted fields.
+	all := c.Request.URL.Query()
+	for k, v := range form {
+		all[k] = append(all[k], v...)
+	}
+	c.Request.PostForm = form
+	c.Request.Form = all
+	c.Request.MultipartForm = &multipart.Form{Value: form, File: map[string][]*multipart.FileHeader{}}
+	return nil
+}
+
+var errFileTooLarge = errors.New("mig: uploaded file is too large")
+
+// limitedPart fails, rather than stopping silently like io.LimitReader,
+// when more than n bytes are read.
+type limitedPart struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedPart) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, errFileTooLarge
+	}
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, errFileTooLarge
+	}
+	return n, err
+}
+
+// uploadError reports exceeded limits as 413 and other errors as the given
+// status, or as they are if it is zero.
+func uploadError(err error, code int) error {
+	var maxBytes *http.MaxBytesError
+	if errors.As(err, &maxBytes) || errors.Is(err, errFileTooLarge) {
+		code = http.StatusRequestEntityTooLarge
+	}
+	var httpErr *HTTPError
+	if code == 0 || errors.As(err, &httpErr) {
+		return err
+	}
+	e := NewHTTPError(code)
+	e.Internal = err
+	return e
+}
+
+func typeAllowed(ctype string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	mediaType, _, _ := mime.ParseMediaType(ctype)
+	for _, a := range allowed {
+		if a == mediaType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(a, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package mig_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/levmv/mig"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func multipartRequest(t *testing.T, files map[string][]byte, fields map[string]string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for k, v := range fields {
+		w.WriteField(k, v)
+	}
+	for name, data := range files {
+		fw, err := w.CreateFormFile("file", name)
+		assertNoError(t, err, "CreateFormFile failed")
+		fw.Write(data)
+	}
+	w.Close()
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+func TestFormFile(t *testing.T) {
+	m := mig.New(context.Background())
+	restoreLogger := setupSilentLogger(m)
+	defer restoreLogger()
+
+	m.Upload = mig.UploadOptions{
+		MaxFileSize:  1024,
+		MaxTotalSize: 4096,
+		AllowedTypes: []string{"image/*"},
+		TempDir:      t.TempDir(),
+	}
+	var tempPath string
+	m.POST("/upload", func(c *mig.Context) error {
+		f, err := c.FormFile("file")
+		if err != nil {
+			return err
+		}
+		tempPath = f.Path
+		r, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		data, _ := io.ReadAll(r)
+		return c.String(http.StatusOK, fmt.Sprintf("%s %s %s %d %d q=%s/%s", c.Request.FormValue("title"), f.Filename, f.ContentType, f.Size, len(data),
+			c.Request.FormValue("q"), c.Request.PostFormValue("q")))
+	})
+
+	rec := httptest.NewRecorder()
+	req := multipartRequest(t, map[string][]byte{"a.png": pngHeader}, map[string]string{"title": "cat"})
+	req.URL.RawQuery = "q=search"
+	m.Mux.ServeHTTP(rec, req)
+	assertEqual(t, http.StatusOK, rec.Code, "Status")
+	assertEqual(t, "cat a.png image/png 16 16 q=search/", rec.Body.String(), "Upload with query values in Form only")
+	if _, err := os.Stat(tempPath); !os.IsNotExist(err) {
+		t.Fatalf("temporary file %s was not removed: %v", tempPath, err)
+	}
+
+	testCases := []struct {
+		name   string
+		req    *http.Request
+		status int
+	}{
+		{"Disallowed type", multipartRequest(t, map[string][]byte{"a.txt": []byte("hello")}, nil), http.StatusUnsupportedMediaType},
+		{"File too large", multipartRequest(t, map[string][]byte{"a.png": append(pngHeader, make([]byte, 2048)...)}, nil), http.StatusRequestEntityTooLarge},
+		{"Body too large", multipartRequest(t, map[string][]byte{"a.png": pngHeader}, map[string]string{"title": strings.Repeat("x", 8192)}), http.StatusRequestEntityTooLarge},
+		{"Missing file", multipartRequest(t, nil, map[string]string{"title": "cat"}), http.StatusBadRequest},
+		{"Not multipart", httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("{}")), http.StatusUnsupportedMediaType},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			m.Mux.ServeHTTP(rec, tc.req)
+			assertEqual(t, tc.status, rec.Code, "Status")
+		})
+	}
+
+	entries, _ := os.ReadDir(m.Upload.TempDir)
+	assertEqual(t, 0, len(entries), "Temporary files left behind")
+}
+
+func TestReadMultipart(t *testing.T) {
+	m := mig.New(context.Background())
+	restoreLogger := setupSilentLogger(m)
+	defer restoreLogger()
+
+	m.POST("/upload", func(c *mig.Context) error {
+		var names []string
+		err := c.ReadMultipart(mig.UploadOptions{}, func(p *mig.UploadPart) error {
+			n, err := io.Copy(io.Discard, p)
+			names = append(names, fmt.Sprintf("%s:%s:%d", p.Filename, p.ContentType, n))
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, strings.Join(names, ","))
+	})
+
+	rec := httptest.NewRecorder()
+	m.Mux.ServeHTTP(rec, multipartRequest(t, map[string][]byte{"a.txt": []byte("hello")}, nil))
+	assertEqual(t, "a.txt:text/plain; charset=utf-8:5", rec.Body.String(), "Streamed parts")
+}